@@ -10,4 +10,5 @@ require (
 	github.com/jinzhu/inflection v1.0.0
 	github.com/mattn/go-sqlite3 v1.11.0
 	github.com/pkg/errors v0.8.1
+	gopkg.in/yaml.v2 v2.4.0
 )
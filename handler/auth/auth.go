@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Authenticator authenticates HTTP requests.
+type Authenticator interface {
+	// Authenticate returns the claims of the caller. Requests without credentials result in nil claims, invalid
+	// credentials result in an error.
+	Authenticate(r *http.Request) (Claims, error)
+}
+
+// Claims contains the verified claims of a caller.
+type Claims map[string]interface{}
+
+// Value returns the claim at a dot separated path (e.g. sub or app_metadata.tenant).
+func (c Claims) Value(path string) (interface{}, bool) {
+	var value interface{} = map[string]interface{}(c)
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// Roles returns the roles contained in the claim at the given path, which is either a single role or a list of roles.
+func (c Claims) Roles(path string) []string {
+	value, _ := c.Value(path)
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var roles []string
+		for _, role := range value {
+			if role, ok := role.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+		return roles
+	}
+
+	return nil
+}
+
+// AuthorizationError is returned if the caller lacks the roles required for an operation or a field.
+type AuthorizationError struct {
+	// Operation describes the denied operation (e.g. delete users or read users.salary).
+	Operation string
+	// Roles are the roles which are allowed to perform the operation.
+	Roles []string
+}
+
+func (e AuthorizationError) Error() string {
+	return fmt.Sprintf("not authorized to %s", e.Operation)
+}
+
+// Extensions adds a machine readable code to the GraphQL error.
+func (e AuthorizationError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":      "FORBIDDEN",
+		"operation": e.Operation,
+	}
+}
+
+type key int
+
+const keyClaims key = iota
+
+// NewContext returns a context containing the claims.
+func NewContext(ctx context.Context, c Claims) context.Context {
+	return context.WithValue(ctx, keyClaims, c)
+}
+
+// FromContext returns the claims of the context, which are nil for unauthenticated requests.
+func FromContext(ctx context.Context) Claims {
+	c, _ := ctx.Value(keyClaims).(Claims)
+	return c
+}
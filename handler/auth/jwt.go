@@ -0,0 +1,287 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"dynamic-graphql-api/handler/config"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// JWTVerifier authenticates requests with a JSON web token in the Authorization header.
+type JWTVerifier struct {
+	algorithm string
+	secret    []byte
+	keys      map[string]*rsa.PublicKey
+	issuer    string
+	audience  string
+}
+
+// NewJWTVerifier creates a verifier of HS256 or RS256 tokens. The public keys of RS256 tokens are read from a JWKS
+// file.
+func NewJWTVerifier(c config.JWT) (*JWTVerifier, error) {
+	v := &JWTVerifier{
+		algorithm: c.Algorithm,
+		issuer:    c.Issuer,
+		audience:  c.Audience,
+	}
+
+	switch c.Algorithm {
+	case "HS256":
+		if c.Secret == "" {
+			return nil, errors.New("missing secret for HS256 tokens")
+		}
+		v.secret = []byte(c.Secret)
+	case "RS256":
+		if c.JWKS == "" {
+			return nil, errors.New("missing JWKS file for RS256 tokens")
+		}
+		keys, err := readJWKS(c.JWKS)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = keys
+	default:
+		return nil, errors.Errorf("unsupported JWT algorithm %s", c.Algorithm)
+	}
+
+	return v, nil
+}
+
+func readJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read JWKS file '%s'", path)
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(content, &jwks); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse JWKS file '%s'", path)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid modulus of key '%s'", k.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid exponent of key '%s'", k.Kid)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > math.MaxInt32 {
+			return nil, errors.Errorf("invalid exponent of key '%s'", k.Kid)
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	}
+	if len(keys) == 0 {
+		return nil, errors.Errorf("JWKS file '%s' contains no RSA keys", path)
+	}
+
+	return keys, nil
+}
+
+// Authenticate verifies the bearer token of the request and returns its claims.
+func (v *JWTVerifier) Authenticate(r *http.Request) (Claims, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, nil
+	}
+
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return nil, errors.New("authorization header is not a bearer token")
+	}
+
+	return v.Verify(strings.TrimSpace(parts[1]))
+}
+
+// Verify verifies the signature and the registered claims of a token and returns its claims.
+func (v *JWTVerifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errors.Wrap(err, "malformed token header")
+	}
+	// the algorithm is fixed by the configuration to prevent algorithm confusion
+	if header.Alg != v.algorithm {
+		return nil, errors.Errorf("unexpected token algorithm %s", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(err, "malformed token signature")
+	}
+	if err := v.verifySignature(header.Kid, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errors.Wrap(err, "malformed token claims")
+	}
+	if claims == nil {
+		return nil, errors.New("malformed token claims")
+	}
+	normalizeNumbers(claims)
+
+	if err := v.verifyClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (v *JWTVerifier) verifySignature(kid string, signed []byte, signature []byte) error {
+	if v.algorithm == "HS256" {
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write(signed)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return errors.New("invalid token signature")
+		}
+
+		return nil
+	}
+
+	hashed := sha256.Sum256(signed)
+	if key, ok := v.keys[kid]; ok {
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signature) != nil {
+			return errors.New("invalid token signature")
+		}
+
+		return nil
+	}
+	if kid != "" {
+		return errors.Errorf("unknown token key '%s'", kid)
+	}
+
+	// tokens without key ID are accepted if any key matches
+	for _, key := range v.keys {
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signature) == nil {
+			return nil
+		}
+	}
+
+	return errors.New("invalid token signature")
+}
+
+func (v *JWTVerifier) verifyClaims(claims Claims) error {
+	now := time.Now().Unix()
+	if exp, ok := claims["exp"]; ok {
+		if exp, ok := numericDate(exp); !ok || now >= exp {
+			return errors.New("token is expired")
+		}
+	}
+	if nbf, ok := claims["nbf"]; ok {
+		if nbf, ok := numericDate(nbf); !ok || now < nbf {
+			return errors.New("token is not valid yet")
+		}
+	}
+
+	if v.issuer != "" && claims["iss"] != v.issuer {
+		return errors.New("unexpected token issuer")
+	}
+
+	if v.audience != "" {
+		var audiences []interface{}
+		switch aud := claims["aud"].(type) {
+		case string:
+			audiences = []interface{}{aud}
+		case []interface{}:
+			audiences = aud
+		}
+
+		found := false
+		for _, aud := range audiences {
+			if aud == v.audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("unexpected token audience")
+		}
+	}
+
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	content, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(content))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+// normalizeNumbers converts JSON numbers to integers if possible and to floats otherwise, so that claims can be used
+// as SQL parameters.
+func normalizeNumbers(object map[string]interface{}) {
+	for key, value := range object {
+		object[key] = normalizeNumber(value)
+	}
+}
+
+func normalizeNumber(value interface{}) interface{} {
+	switch value := value.(type) {
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i
+		}
+		f, _ := value.Float64()
+		return f
+	case map[string]interface{}:
+		normalizeNumbers(value)
+	case []interface{}:
+		for i := range value {
+			value[i] = normalizeNumber(value[i])
+		}
+	}
+
+	return value
+}
+
+func numericDate(value interface{}) (int64, bool) {
+	switch value := value.(type) {
+	case int64:
+		return value, true
+	case float64:
+		return int64(value), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"dynamic-graphql-api/handler/config"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	content, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(content)
+}
+
+func signHS256(t *testing.T, secret string, header map[string]interface{}, claims map[string]interface{}) string {
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, header map[string]interface{}, claims map[string]interface{}) string {
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	hashed := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// tamper replaces the claims of a signed token.
+func tamper(token string, claims string) string {
+	parts := strings.Split(token, ".")
+	return parts[0] + "." + claims + "." + parts[2]
+}
+
+func TestJWTVerifierHS256(t *testing.T) {
+	v, err := NewJWTVerifier(config.JWT{Algorithm: "HS256", Secret: "secret", Issuer: "issuer", Audience: "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hs256 := map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+	now := time.Now().Unix()
+	valid := map[string]interface{}{"sub": "1", "iss": "issuer", "aud": "api", "exp": now + 60}
+
+	with := func(key string, value interface{}) map[string]interface{} {
+		claims := map[string]interface{}{}
+		for k, v := range valid {
+			claims[k] = v
+		}
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		err   string
+	}{
+		{"valid", signHS256(t, "secret", hs256, valid), ""},
+		{"audience list", signHS256(t, "secret", hs256, with("aud", []string{"other", "api"})), ""},
+		{"expired", signHS256(t, "secret", hs256, with("exp", now-1)), "token is expired"},
+		{"malformed expiry", signHS256(t, "secret", hs256, with("exp", "tomorrow")), "token is expired"},
+		{"not valid yet", signHS256(t, "secret", hs256, with("nbf", now+60)), "token is not valid yet"},
+		{"wrong secret", signHS256(t, "other", hs256, valid), "invalid token signature"},
+		{"wrong algorithm", signHS256(t, "secret", map[string]interface{}{"alg": "RS256"}, valid), "unexpected token algorithm RS256"},
+		{"none algorithm", encodeSegment(t, map[string]interface{}{"alg": "none"}) + "." + encodeSegment(t, valid) + ".", "unexpected token algorithm none"},
+		{"wrong audience", signHS256(t, "secret", hs256, with("aud", "other")), "unexpected token audience"},
+		{"missing audience", signHS256(t, "secret", hs256, with("aud", nil)), "unexpected token audience"},
+		{"wrong issuer", signHS256(t, "secret", hs256, with("iss", "other")), "unexpected token issuer"},
+		{"tampered claims", tamper(signHS256(t, "secret", hs256, valid), encodeSegment(t, with("sub", "2"))), "invalid token signature"},
+		{"malformed", "token", "malformed token"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims, err := v.Verify(test.token)
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				if claims["sub"] != "1" {
+					t.Fatalf("unexpected claims %v", claims)
+				}
+				return
+			}
+
+			if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+				t.Fatalf("expected error %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestJWTVerifierRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "jwks.json")
+	jwks := map[string]interface{}{
+		"keys": []map[string]interface{}{{
+			"kty": "RSA",
+			"kid": "key",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+	content, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := NewJWTVerifier(config.JWT{Algorithm: "RS256", JWKS: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := map[string]interface{}{"sub": "1", "exp": time.Now().Unix() + 60}
+
+	tests := []struct {
+		name  string
+		token string
+		err   string
+	}{
+		{"valid", signRS256(t, key, map[string]interface{}{"alg": "RS256", "kid": "key"}, claims), ""},
+		{"without key ID", signRS256(t, key, map[string]interface{}{"alg": "RS256"}, claims), ""},
+		{"unknown key ID", signRS256(t, key, map[string]interface{}{"alg": "RS256", "kid": "other"}, claims), "unknown token key 'other'"},
+		{"wrong key", signRS256(t, otherKey, map[string]interface{}{"alg": "RS256", "kid": "key"}, claims), "invalid token signature"},
+		// an HS256 token signed with the public key must not be accepted
+		{"algorithm confusion", signHS256(t, string(key.N.Bytes()), map[string]interface{}{"alg": "HS256", "kid": "key"}, claims), "unexpected token algorithm HS256"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := v.Verify(test.token)
+			if test.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != test.err {
+				t.Fatalf("expected error %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestJWTVerifierAuthenticate(t *testing.T) {
+	v, err := NewJWTVerifier(config.JWT{Algorithm: "HS256", Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := signHS256(t, "secret", map[string]interface{}{"alg": "HS256"}, map[string]interface{}{"sub": "1"})
+
+	tests := []struct {
+		name   string
+		header string
+		claims bool
+		err    bool
+	}{
+		{"without header", "", false, false},
+		{"bearer token", "Bearer " + token, true, false},
+		{"lower case scheme", "bearer " + token, true, false},
+		{"basic credentials", "Basic dXNlcjpwYXNz", false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodGet, "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.header != "" {
+				r.Header.Set("Authorization", test.header)
+			}
+
+			claims, err := v.Authenticate(r)
+			if (err != nil) != test.err {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if (claims != nil) != test.claims {
+				t.Fatalf("unexpected claims %v", claims)
+			}
+		})
+	}
+}
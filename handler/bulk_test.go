@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const bulkSchema = `
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+INSERT INTO users (name) VALUES ('a'), ('b'), ('c');
+`
+
+func TestCreateMany(t *testing.T) {
+	s := newTestServer(t, bulkSchema, "")
+	defer s.Close()
+
+	response := s.do("", `mutation {
+		createManyUsers(input: [{clientMutationId: "1", name: "d"}, {clientMutationId: "2", name: "e"}, {clientMutationId: "3", name: "f"}]) {
+			clientMutationId
+			user { id name }
+		}
+	}`)
+	if len(response.Errors) > 0 {
+		t.Fatalf("unexpected errors %v", response.errorMessages())
+	}
+
+	// the payloads are in the order of the inputs and refer to the inserted rows
+	payloads := response.Data["createManyUsers"].([]interface{})
+	for i, name := range []string{"d", "e", "f"} {
+		payload := payloads[i].(map[string]interface{})
+		if payload["clientMutationId"] != fmt.Sprint(i+1) {
+			t.Errorf("payload %d has clientMutationId %v", i, payload["clientMutationId"])
+		}
+		user := payload["user"].(map[string]interface{})
+		if user["name"] != name {
+			t.Errorf("payload %d refers to %v, expected %s", i, user["name"], name)
+		}
+		if id := testID("User", 4+i); user["id"] != id {
+			t.Errorf("payload %d has id %v, expected %s", i, user["id"], id)
+		}
+	}
+	if count := s.count("SELECT COUNT(*) FROM users"); count != 6 {
+		t.Errorf("%d rows after the creation, expected 6", count)
+	}
+}
+
+// Rows inserted by triggers take ids between the ids of the created rows.
+func TestCreateManyWithTrigger(t *testing.T) {
+	s := newTestServer(t, bulkSchema+`
+		CREATE TRIGGER copy_e AFTER INSERT ON users WHEN NEW.name = 'e' BEGIN
+			INSERT INTO users (name) VALUES ('copy of e');
+		END;
+	`, "")
+	defer s.Close()
+
+	response := s.do("", `mutation {
+		createManyUsers(input: [{clientMutationId: "1", name: "d"}, {clientMutationId: "2", name: "e"}, {clientMutationId: "3", name: "f"}]) {
+			user { id name }
+		}
+	}`)
+	if len(response.Errors) > 0 {
+		t.Fatalf("unexpected errors %v", response.errorMessages())
+	}
+
+	payloads := response.Data["createManyUsers"].([]interface{})
+	for i, name := range []string{"d", "e", "f"} {
+		user := payloads[i].(map[string]interface{})["user"].(map[string]interface{})
+		if user["name"] != name {
+			t.Errorf("payload %d refers to %v, expected %s", i, user["name"], name)
+		}
+	}
+	if count := s.count("SELECT COUNT(*) FROM users WHERE (id = 4 AND name = 'd') OR (id = 5 AND name = 'e') OR (id = 7 AND name = 'f')"); count != 3 {
+		t.Errorf("%d rows match their inputs, expected 3", count)
+	}
+}
+
+// Many inputs are inserted by several statements, because the number of variables of a statement is limited.
+func TestCreateManyInChunks(t *testing.T) {
+	s := newTestServer(t, bulkSchema, "")
+	defer s.Close()
+
+	var inputs []string
+	for i := 0; i < 1500; i++ {
+		inputs = append(inputs, fmt.Sprintf(`{clientMutationId: "%d", name: "n%d"}`, i, i))
+	}
+	response := s.do("", fmt.Sprintf(`mutation { createManyUsers(input: [%s]) { user { id } } }`, strings.Join(inputs, ", ")))
+	if len(response.Errors) > 0 {
+		t.Fatalf("unexpected errors %v", response.errorMessages())
+	}
+
+	payloads := response.Data["createManyUsers"].([]interface{})
+	for _, i := range []int{0, 998, 999, 1499} {
+		id := payloads[i].(map[string]interface{})["user"].(map[string]interface{})["id"]
+		if expected := testID("User", 4+i); id != expected {
+			t.Errorf("payload %d has id %v, expected %s", i, id, expected)
+		}
+	}
+	if count := s.count("SELECT COUNT(*) FROM users WHERE name = 'n' || (id - 4)"); count != 1500 {
+		t.Errorf("%d rows match their inputs, expected 1500", count)
+	}
+}
+
+func TestUpdateAndDeleteMany(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		message string
+		check   string
+		count   int
+	}{
+		{
+			name:  "update",
+			query: fmt.Sprintf(`mutation { updateManyUsers(input: [{clientMutationId: "1", id: "%s", name: "x"}, {clientMutationId: "2", id: "%s", name: "y"}]) { clientMutationId } }`, testID("User", 1), testID("User", 3)),
+			check: "SELECT COUNT(*) FROM users WHERE (id = 1 AND name = 'x') OR (id = 2 AND name = 'b') OR (id = 3 AND name = 'y')",
+			count: 3,
+		},
+		{
+			name:    "update of a duplicate id",
+			query:   fmt.Sprintf(`mutation { updateManyUsers(input: [{clientMutationId: "1", id: "%s", name: "x"}, {clientMutationId: "2", id: "%s", name: "y"}]) { clientMutationId } }`, testID("User", 1), testID("User", 1)),
+			message: "duplicate id",
+			check:   "SELECT COUNT(*) FROM users WHERE name IN ('x', 'y')",
+			count:   0,
+		},
+		{
+			name:    "update of a missing row",
+			query:   fmt.Sprintf(`mutation { updateManyUsers(input: [{clientMutationId: "1", id: "%s", name: "x"}, {clientMutationId: "2", id: "%s", name: "y"}]) { clientMutationId } }`, testID("User", 1), testID("User", 9)),
+			message: "1 of 2 rows not found",
+			check:   "SELECT COUNT(*) FROM users WHERE name IN ('x', 'y')",
+			count:   0,
+		},
+		{
+			name:  "delete",
+			query: fmt.Sprintf(`mutation { deleteManyUsers(input: [{clientMutationId: "1", id: "%s"}, {clientMutationId: "2", id: "%s"}]) { clientMutationId } }`, testID("User", 1), testID("User", 3)),
+			check: "SELECT COUNT(*) FROM users WHERE id = 2",
+			count: 1,
+		},
+		{
+			name:    "delete of a duplicate id",
+			query:   fmt.Sprintf(`mutation { deleteManyUsers(input: [{clientMutationId: "1", id: "%s"}, {clientMutationId: "2", id: "%s"}]) { clientMutationId } }`, testID("User", 2), testID("User", 2)),
+			message: "duplicate id",
+			check:   "SELECT COUNT(*) FROM users",
+			count:   3,
+		},
+		{
+			name:    "delete of a missing row",
+			query:   fmt.Sprintf(`mutation { deleteManyUsers(input: [{clientMutationId: "1", id: "%s"}, {clientMutationId: "2", id: "%s"}]) { clientMutationId } }`, testID("User", 2), testID("User", 9)),
+			message: "1 of 2 rows not found",
+			check:   "SELECT COUNT(*) FROM users",
+			count:   3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestServer(t, bulkSchema, "")
+			defer s.Close()
+
+			response := s.do("", test.query)
+			switch messages := strings.Join(response.errorMessages(), "; "); {
+			case test.message == "" && messages != "":
+				t.Errorf("unexpected errors %s", messages)
+			case !strings.Contains(messages, test.message):
+				t.Errorf("expected an error containing %q, got %q", test.message, messages)
+			}
+			if count := s.count(test.check); count != test.count {
+				t.Errorf("%s returned %d, expected %d", test.check, count, test.count)
+			}
+		})
+	}
+}
@@ -0,0 +1,390 @@
+package complexity
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/pkg/errors"
+)
+
+// Options configures the page sizes assumed for connections.
+type Options struct {
+	// DefaultPageSize is the page size of connections without first or last, whose objects have no default page
+	// size.
+	DefaultPageSize int
+	// PageSizes maps the names of object types to the page sizes of their connections.
+	PageSizes map[string]PageSize
+	// RequirePageSize rejects connections without first or last.
+	RequirePageSize bool
+	// DefaultDepth is the amount of levels of recursive connections without depth.
+	DefaultDepth int
+}
+
+// PageSize contains the default page size of connections without first or last and the maximum of first and last.
+// Zero values disable them.
+type PageSize struct {
+	Default int
+	Max     int
+}
+
+// Result contains the depth and the cost of an operation.
+type Result struct {
+	Depth int
+	Cost  int
+}
+
+type analyzer struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	options   Options
+}
+
+// Analyze calculates the depth and the cost of an operation of a parsed document. Every object field costs 1, the
+// cost of the selections of a connection is multiplied by its page size. Recursive connections additionally cost their
+// page size per traversed level. Fields with list arguments (e.g. IDs or inputs of bulk mutations) cost 1 and their
+// selections per item. Introspection fields are not counted.
+// Invalid documents are analyzed as far as possible, their errors are reported by the validation.
+func Analyze(s *graphql.Schema, document *ast.Document, operationName string, variables map[string]interface{}, o Options) (Result, error) {
+	a := analyzer{
+		schema:    s,
+		fragments: map[string]*ast.FragmentDefinition{},
+		variables: map[string]interface{}{},
+		options:   o,
+	}
+
+	var operation *ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.FragmentDefinition:
+			a.fragments[definition.Name.Value] = definition
+		case *ast.OperationDefinition:
+			if operationName == "" || (definition.Name != nil && definition.Name.Value == operationName) {
+				if operation == nil {
+					operation = definition
+				}
+			}
+		}
+	}
+	if operation == nil {
+		return Result{}, nil
+	}
+
+	// default values of variables are used if the variables are missing
+	for _, definition := range operation.VariableDefinitions {
+		if definition.DefaultValue != nil {
+			a.variables[definition.Variable.Name.Value] = definition.DefaultValue.GetValue()
+		}
+	}
+	for name, value := range variables {
+		a.variables[name] = value
+	}
+
+	var rootType *graphql.Object
+	switch operation.Operation {
+	case ast.OperationTypeMutation:
+		rootType = s.MutationType()
+	case ast.OperationTypeSubscription:
+		rootType = s.SubscriptionType()
+	default:
+		rootType = s.QueryType()
+	}
+	if rootType == nil {
+		return Result{}, nil
+	}
+
+	return a.selectionSet(rootType, operation.SelectionSet, map[string]bool{})
+}
+
+func (a analyzer) selectionSet(parentType graphql.Type, selectionSet *ast.SelectionSet, visitedFragments map[string]bool) (Result, error) {
+	var result Result
+	if selectionSet == nil {
+		return result, nil
+	}
+
+	for _, selection := range selectionSet.Selections {
+		var (
+			selectionResult Result
+			err             error
+		)
+		switch selection := selection.(type) {
+		case *ast.Field:
+			selectionResult, err = a.field(parentType, selection, visitedFragments)
+		case *ast.InlineFragment:
+			fragmentType := parentType
+			if selection.TypeCondition != nil {
+				fragmentType = a.schema.Type(selection.TypeCondition.Name.Value)
+			}
+			selectionResult, err = a.selectionSet(fragmentType, selection.SelectionSet, visitedFragments)
+		case *ast.FragmentSpread:
+			fragment, ok := a.fragments[selection.Name.Value]
+			if !ok || visitedFragments[selection.Name.Value] {
+				continue
+			}
+
+			visited := map[string]bool{selection.Name.Value: true}
+			for name := range visitedFragments {
+				visited[name] = true
+			}
+			selectionResult, err = a.selectionSet(a.schema.Type(fragment.TypeCondition.Name.Value), fragment.SelectionSet, visited)
+		}
+		if err != nil {
+			return result, err
+		}
+
+		if selectionResult.Depth > result.Depth {
+			result.Depth = selectionResult.Depth
+		}
+		result.Cost = add(result.Cost, selectionResult.Cost)
+	}
+
+	return result, nil
+}
+
+func (a analyzer) field(parentType graphql.Type, field *ast.Field, visitedFragments map[string]bool) (Result, error) {
+	fieldName := field.Name.Value
+	if len(fieldName) > 1 && fieldName[:2] == "__" {
+		return Result{}, nil
+	}
+
+	var fieldDefinitions graphql.FieldDefinitionMap
+	switch parentType := parentType.(type) {
+	case *graphql.Object:
+		fieldDefinitions = parentType.Fields()
+	case *graphql.Interface:
+		fieldDefinitions = parentType.Fields()
+	}
+	fieldDefinition, ok := fieldDefinitions[fieldName]
+	if !ok {
+		return Result{}, nil
+	}
+
+	fieldType := unwrap(fieldDefinition.Type)
+	if field.SelectionSet == nil {
+		// scalars are resolved with their objects
+		return Result{Depth: 1}, nil
+	}
+
+	result, err := a.selectionSet(fieldType, field.SelectionSet, visitedFragments)
+	if err != nil {
+		return result, err
+	}
+	result.Depth++
+
+	if isConnection(fieldDefinition) {
+		pageSize, err := a.pageSize(field, a.options.PageSizes[nodeType(fieldType)])
+		if err != nil {
+			return result, err
+		}
+		result.Cost = multiply(result.Cost, pageSize)
+
+		if isRecursive(fieldDefinition) {
+			result.Cost = add(result.Cost, multiply(pageSize, a.depth(field)))
+		}
+	}
+
+	if items, ok := a.listLength(field, fieldDefinition); ok {
+		result.Cost = multiply(add(result.Cost, 1), items)
+		return result, nil
+	}
+	result.Cost = add(result.Cost, 1)
+
+	return result, nil
+}
+
+// value returns the value of an argument, variables are replaced by their values.
+func (a analyzer) value(argument *ast.Argument) interface{} {
+	if variable, ok := argument.Value.(*ast.Variable); ok {
+		return a.variables[variable.Name.Value]
+	}
+
+	return argument.Value.GetValue()
+}
+
+// depth returns the amount of levels traversed by a recursive connection.
+func (a analyzer) depth(field *ast.Field) int {
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "depth" {
+			continue
+		}
+
+		if depth, ok := toInt(a.value(argument)); ok && depth > 0 {
+			return depth
+		}
+	}
+
+	return a.options.DefaultDepth
+}
+
+// listLength returns the length of the longest list argument of a field and whether the field has list arguments.
+// Single values of list arguments are coerced to lists of one item.
+func (a analyzer) listLength(field *ast.Field, fieldDefinition *graphql.FieldDefinition) (int, bool) {
+	listArguments := map[string]bool{}
+	for _, argument := range fieldDefinition.Args {
+		t := argument.Type
+		if nonNull, ok := t.(*graphql.NonNull); ok {
+			t = nonNull.OfType
+		}
+		if _, ok := t.(*graphql.List); ok {
+			listArguments[argument.Name()] = true
+		}
+	}
+	if len(listArguments) == 0 {
+		return 0, false
+	}
+
+	length := 0
+	for _, argument := range field.Arguments {
+		if !listArguments[argument.Name.Value] {
+			continue
+		}
+
+		var items int
+		if list, ok := argument.Value.(*ast.ListValue); ok {
+			items = len(list.Values)
+		} else {
+			switch value := a.value(argument).(type) {
+			case nil:
+				items = 0
+			case []interface{}:
+				items = len(value)
+			default:
+				items = 1
+			}
+		}
+		if items > length {
+			length = items
+		}
+	}
+
+	return length, true
+}
+
+func (a analyzer) pageSize(field *ast.Field, pageSizes PageSize) (int, error) {
+	var (
+		pageSize int
+		found    bool
+	)
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "first" && argument.Name.Value != "last" {
+			continue
+		}
+
+		size, ok := toInt(a.value(argument))
+		if !ok {
+			continue
+		}
+		if size < 0 {
+			size = 0
+		}
+		if pageSizes.Max > 0 && size > pageSizes.Max {
+			return 0, errors.Errorf("%s of connection %s exceeds the maximum page size of %d", argument.Name.Value, field.Name.Value, pageSizes.Max)
+		}
+		if !found || size > pageSize {
+			pageSize = size
+		}
+		found = true
+	}
+
+	if !found {
+		if a.options.RequirePageSize {
+			return 0, errors.Errorf("connection %s requires first or last", field.Name.Value)
+		}
+		if pageSizes.Default > 0 {
+			return pageSizes.Default, nil
+		}
+
+		return a.options.DefaultPageSize, nil
+	}
+
+	return pageSize, nil
+}
+
+func isConnection(fieldDefinition *graphql.FieldDefinition) bool {
+	for _, argument := range fieldDefinition.Args {
+		if argument.Name() == "first" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isRecursive(fieldDefinition *graphql.FieldDefinition) bool {
+	for _, argument := range fieldDefinition.Args {
+		if argument.Name() == "depth" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nodeType returns the name of the type of the nodes of a connection type.
+func nodeType(connectionType graphql.Type) string {
+	connection, ok := connectionType.(*graphql.Object)
+	if !ok {
+		return ""
+	}
+	edges, ok := connection.Fields()["edges"]
+	if !ok {
+		return ""
+	}
+	edge, ok := unwrap(edges.Type).(*graphql.Object)
+	if !ok {
+		return ""
+	}
+	node, ok := edge.Fields()["node"]
+	if !ok {
+		return ""
+	}
+
+	return unwrap(node.Type).Name()
+}
+
+func unwrap(t graphql.Type) graphql.Type {
+	for {
+		switch wrapped := t.(type) {
+		case *graphql.NonNull:
+			t = wrapped.OfType
+		case *graphql.List:
+			t = wrapped.OfType
+		default:
+			return t
+		}
+	}
+}
+
+func toInt(value interface{}) (int, bool) {
+	switch value := value.(type) {
+	case int:
+		return value, true
+	case float64:
+		return int(value), true
+	case string:
+		// literal values of the AST are strings
+		i, err := strconv.Atoi(value)
+		return i, err == nil
+	}
+
+	return 0, false
+}
+
+// add and multiply saturate instead of overflowing on huge costs.
+func add(a, b int) int {
+	if a > math.MaxInt32-b {
+		return math.MaxInt32
+	}
+
+	return a + b
+}
+
+func multiply(a, b int) int {
+	if b > 0 && a > math.MaxInt32/b {
+		return math.MaxInt32
+	}
+
+	return a * b
+}
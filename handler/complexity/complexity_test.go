@@ -0,0 +1,170 @@
+package complexity
+
+import (
+	"math"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// newTestSchema creates a schema of users, who have a connection of friends and a recursive connection of
+// descendants, and lists of users by their IDs.
+func newTestSchema(t *testing.T) *graphql.Schema {
+	user := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":   &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"name": &graphql.Field{Type: graphql.String},
+		},
+	})
+	edge := graphql.NewObject(graphql.ObjectConfig{
+		Name: "UsersEdge",
+		Fields: graphql.Fields{
+			"node": &graphql.Field{Type: graphql.NewNonNull(user)},
+		},
+	})
+	connection := graphql.NewObject(graphql.ObjectConfig{
+		Name: "UsersConnection",
+		Fields: graphql.Fields{
+			"edges": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(edge)))},
+		},
+	})
+	connectionArgs := graphql.FieldConfigArgument{
+		"first": &graphql.ArgumentConfig{Type: graphql.Int},
+		"last":  &graphql.ArgumentConfig{Type: graphql.Int},
+	}
+	user.AddFieldConfig("friends", &graphql.Field{Type: connection, Args: connectionArgs})
+	user.AddFieldConfig("descendants", &graphql.Field{Type: connection, Args: graphql.FieldConfigArgument{
+		"first": &graphql.ArgumentConfig{Type: graphql.Int},
+		"depth": &graphql.ArgumentConfig{Type: graphql.Int},
+	}})
+
+	ids := graphql.FieldConfigArgument{
+		"ids": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID)))},
+	}
+	s, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"users": &graphql.Field{Type: connection, Args: connectionArgs},
+				"user":  &graphql.Field{Type: user},
+				"nodes": &graphql.Field{Type: graphql.NewList(user), Args: ids},
+			},
+		}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Mutation",
+			Fields: graphql.Fields{
+				"deleteManyUsers": &graphql.Field{Type: graphql.NewList(user), Args: ids},
+			},
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &s
+}
+
+func analyze(t *testing.T, s *graphql.Schema, query string, variables map[string]interface{}, o Options) (Result, error) {
+	document, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(query)})})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return Analyze(s, document, "", variables, o)
+}
+
+func TestAnalyze(t *testing.T) {
+	s := newTestSchema(t)
+	defaults := Options{DefaultPageSize: 100, DefaultDepth: 10}
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		options   Options
+		result    Result
+	}{
+		{"scalar fields", `{ user { id name } }`, nil, defaults, Result{Depth: 2, Cost: 1}},
+		{"introspection", `{ __schema { types { name } } }`, nil, defaults, Result{}},
+		{"first", `{ users(first: 10) { edges { node { name } } } }`, nil, defaults, Result{Depth: 4, Cost: 21}},
+		{"last", `{ users(last: 3) { edges { node { name } } } }`, nil, defaults, Result{Depth: 4, Cost: 7}},
+		{"default page size", `{ users { edges { node { name } } } }`, nil, defaults, Result{Depth: 4, Cost: 201}},
+		{"nested connections", `{ users(first: 10) { edges { node { friends(first: 5) { edges { node { id } } } } } } }`, nil, defaults, Result{Depth: 7, Cost: 131}},
+		{"variable", `query($n: Int) { users(first: $n) { edges { node { name } } } }`, map[string]interface{}{"n": float64(3)}, defaults, Result{Depth: 4, Cost: 7}},
+		{"default value", `query($n: Int = 4) { users(first: $n) { edges { node { name } } } }`, nil, defaults, Result{Depth: 4, Cost: 9}},
+		{
+			// edges and node cost 2 per page
+			"fragments",
+			`query { users(first: 2) { ...users } } fragment users on UsersConnection { edges { node { name } } }`,
+			nil, defaults, Result{Depth: 4, Cost: 5},
+		},
+		{
+			"cyclic fragments",
+			`query { user { ...a } } fragment a on User { friends(first: 2) { edges { node { ...a } } } }`,
+			nil, defaults, Result{Depth: 4, Cost: 6},
+		},
+		{"recursive connection", `{ user { descendants(first: 5, depth: 3) { edges { node { id } } } } }`, nil, defaults, Result{Depth: 5, Cost: 27}},
+		{"default depth", `{ user { descendants(first: 5) { edges { node { id } } } } }`, nil, defaults, Result{Depth: 5, Cost: 62}},
+		{"list argument", `{ nodes(ids: ["a", "b", "c"]) { id } }`, nil, defaults, Result{Depth: 2, Cost: 3}},
+		{"list variable", `query($ids: [ID!]!) { nodes(ids: $ids) { id } }`, map[string]interface{}{"ids": []interface{}{"a", "b"}}, defaults, Result{Depth: 2, Cost: 2}},
+		{"coerced list argument", `{ nodes(ids: "a") { id } }`, nil, defaults, Result{Depth: 2, Cost: 1}},
+		{"coerced list variable", `query($ids: [ID!]!) { nodes(ids: $ids) { id } }`, map[string]interface{}{"ids": "a"}, defaults, Result{Depth: 2, Cost: 1}},
+		{"list argument of a mutation", `mutation { deleteManyUsers(ids: ["a", "b"]) { friends(first: 2) { edges { node { id } } } } }`, nil, defaults, Result{Depth: 5, Cost: 12}},
+		{"saturated cost", `{ users(first: 100000) { edges { node { friends(first: 100000) { edges { node { id } } } } } } }`, nil, defaults, Result{Depth: 7, Cost: math.MaxInt32}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := analyze(t, s, test.query, test.variables, test.options)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if result != test.result {
+				t.Fatalf("expected %+v, got %+v", test.result, result)
+			}
+		})
+	}
+}
+
+func TestAnalyzePageSizeLimits(t *testing.T) {
+	s := newTestSchema(t)
+
+	maxPageSize := Options{PageSizes: map[string]PageSize{"User": {Max: 20}}}
+	if _, err := analyze(t, s, `{ users(first: 50) { edges { node { name } } } }`, nil, maxPageSize); err == nil {
+		t.Error("accepted a page size exceeding the maximum")
+	}
+	if _, err := analyze(t, s, `{ users(first: 20) { edges { node { name } } } }`, nil, maxPageSize); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := analyze(t, s, `{ users { edges { node { name } } } }`, nil, Options{RequirePageSize: true}); err == nil {
+		t.Error("accepted a connection without first or last")
+	}
+	if _, err := analyze(t, s, `{ users(last: 1) { edges { node { name } } } }`, nil, Options{RequirePageSize: true}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestAnalyzeObjectPageSizes(t *testing.T) {
+	s := newTestSchema(t)
+	query := `{ users { edges { node { name } } } }`
+
+	// the page size of the object takes precedence over the default page size
+	result, err := analyze(t, s, query, nil, Options{DefaultPageSize: 100, PageSizes: map[string]PageSize{"User": {Default: 5}}})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if result.Cost != 11 {
+		t.Errorf("expected cost 11, got %d", result.Cost)
+	}
+
+	result, err = analyze(t, s, query, nil, Options{DefaultPageSize: 100, PageSizes: map[string]PageSize{"User": {Max: 200}}})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if result.Cost != 201 {
+		t.Errorf("expected cost 201, got %d", result.Cost)
+	}
+}
@@ -0,0 +1,204 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Config represents the configuration file of the API server.
+type Config struct {
+	Inflection Inflection       `yaml:"inflection" json:"inflection"`
+	Tables     map[string]Table `yaml:"tables" json:"tables"`
+	IDs        IDs              `yaml:"ids" json:"ids"`
+	Auth       Auth             `yaml:"auth" json:"auth"`
+	Limits     Limits           `yaml:"limits" json:"limits"`
+	Pagination Pagination       `yaml:"pagination" json:"pagination"`
+
+	PersistedQueries PersistedQueries `yaml:"persistedQueries" json:"persistedQueries"`
+	RateLimit        RateLimit        `yaml:"rateLimit" json:"rateLimit"`
+}
+
+// RateLimit limits the operations per client with token buckets, keyed by the subject of authenticated callers or
+// the client IP.
+type RateLimit struct {
+	// Queries is the budget of queries.
+	Queries Budget `yaml:"queries" json:"queries"`
+	// Mutations is the budget of mutations.
+	Mutations Budget `yaml:"mutations" json:"mutations"`
+	// Weighted consumes the cost of operations instead of one token per operation.
+	Weighted bool `yaml:"weighted" json:"weighted"`
+	// KeyClaim is the claim identifying authenticated callers, defaults to sub.
+	KeyClaim string `yaml:"keyClaim" json:"keyClaim"`
+	// TrustProxy reads the client IP from the X-Forwarded-For header.
+	TrustProxy bool `yaml:"trustProxy" json:"trustProxy"`
+}
+
+// Budget is a token bucket, which is disabled if its rate is 0.
+type Budget struct {
+	// Rate is the amount of tokens refilled per second.
+	Rate float64 `yaml:"rate" json:"rate"`
+	// Burst is the capacity of the bucket, defaults to the rate (at least 1).
+	Burst int `yaml:"burst" json:"burst"`
+}
+
+// PersistedQueries contains the persisted queries, which are requested by their SHA-256 hash.
+type PersistedQueries struct {
+	// Enabled registers queries sent together with their hash automatically (automatic persisted queries).
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Store is either memory (default) or sqlite.
+	Store string `yaml:"store" json:"store"`
+	// MaxEntries limits the amount of queries in the memory store, defaults to 1000.
+	MaxEntries int `yaml:"maxEntries" json:"maxEntries"`
+	// Path is the path to the database of the sqlite store.
+	Path string `yaml:"path" json:"path"`
+	// Manifest is the path to a JSON file of pre-registered queries, either an object mapping hashes to queries or
+	// an Apollo persisted query manifest.
+	Manifest string `yaml:"manifest" json:"manifest"`
+	// Strict only executes the queries of the manifest.
+	Strict bool `yaml:"strict" json:"strict"`
+}
+
+// Pagination contains the page sizes of connections, which are also used by the cost analysis of the limits.
+type Pagination struct {
+	// DefaultPageSize is used for connections without first or last.
+	DefaultPageSize int `yaml:"defaultPageSize" json:"defaultPageSize"`
+	// MaxPageSize rejects connections with a larger first or last.
+	MaxPageSize int `yaml:"maxPageSize" json:"maxPageSize"`
+}
+
+// Limits restrict the complexity of operations, which are rejected before their execution.
+type Limits struct {
+	// MaxDepth is the maximum nesting of fields.
+	MaxDepth int `yaml:"maxDepth" json:"maxDepth"`
+	// MaxCost is the maximum cost, every object costs 1 and connections multiply the cost of their selections by
+	// their page size. Connections without first, last and a default page size are assumed to return 100 objects.
+	MaxCost int `yaml:"maxCost" json:"maxCost"`
+	// RequirePageSize rejects connections without first or last.
+	RequirePageSize bool `yaml:"requirePageSize" json:"requirePageSize"`
+}
+
+// Auth contains the authentication of requests.
+type Auth struct {
+	// Required rejects requests without credentials, otherwise they are executed without claims.
+	Required bool `yaml:"required" json:"required"`
+	// JWT verifies bearer tokens of the Authorization header.
+	JWT *JWT `yaml:"jwt" json:"jwt"`
+	// RolesClaim is the claim containing the role or the list of roles of the caller, defaults to roles.
+	RolesClaim string `yaml:"rolesClaim" json:"rolesClaim"`
+}
+
+// JWT contains the verification of JSON web tokens.
+type JWT struct {
+	// Algorithm is either HS256 (with Secret) or RS256 (with JWKS).
+	Algorithm string `yaml:"algorithm" json:"algorithm"`
+	// Secret is the shared secret of HS256 tokens.
+	Secret string `yaml:"secret" json:"secret"`
+	// JWKS is the path to a JSON web key set file containing the public keys of RS256 tokens.
+	JWKS string `yaml:"jwks" json:"jwks"`
+	// Issuer is compared to the iss claim if set.
+	Issuer string `yaml:"issuer" json:"issuer"`
+	// Audience must be contained in the aud claim if set.
+	Audience string `yaml:"audience" json:"audience"`
+}
+
+// IDs contains the protection of global IDs and pagination cursors against forging.
+type IDs struct {
+	// Mode is either hmac (signed) or aes (encrypted), defaults to hmac if a key is given.
+	Mode string `yaml:"mode" json:"mode"`
+	// Key protects all generated IDs.
+	Key string `yaml:"key" json:"key"`
+	// OldKeys are still accepted when parsing IDs, which allows rotating the key.
+	OldKeys []string `yaml:"oldKeys" json:"oldKeys"`
+}
+
+// Inflection contains global overrides of the pluralization rules.
+type Inflection struct {
+	// Uncountable words are never pluralized or singularized (e.g. data).
+	Uncountable []string `yaml:"uncountable" json:"uncountable"`
+	// Irregular maps singular words to their plural (e.g. person: people).
+	Irregular map[string]string `yaml:"irregular" json:"irregular"`
+}
+
+// Table contains the configuration of a table, keyed by the table name.
+type Table struct {
+	// Name overrides the name of the object type.
+	Name string `yaml:"name" json:"name"`
+	// Plural overrides the plural of the object name.
+	Plural string `yaml:"plural" json:"plural"`
+	// Hidden removes the table from the schema.
+	Hidden bool `yaml:"hidden" json:"hidden"`
+	// JoinTable forces whether the table is treated as join table.
+	JoinTable *bool `yaml:"joinTable" json:"joinTable"`
+	// Columns contains the configuration of columns, keyed by the column name.
+	Columns map[string]Column `yaml:"columns" json:"columns"`
+	// Fields renames generated fields (e.g. back-references), keyed by the generated name.
+	Fields map[string]string `yaml:"fields" json:"fields"`
+	// Policies restrict the accessible rows with SQL predicates keyed by the operation (read, update or delete).
+	// Claims of the caller are referenced with $claims.<name> (e.g. owner_id = $claims.sub).
+	Policies map[string]string `yaml:"policies" json:"policies"`
+	// Permissions restrict operations (read, create, update, delete or restore) to the listed roles. For join tables
+	// create and delete restrict associating and disassociating. Restore also restricts reading soft-deleted rows.
+	Permissions map[string][]string `yaml:"permissions" json:"permissions"`
+	// Pagination overrides the page sizes of connections of the object.
+	Pagination Pagination `yaml:"pagination" json:"pagination"`
+	// Version is the column of optimistic concurrency control, which updates require and compare. Integer columns
+	// are incremented by every change, DateTime columns (e.g. updated_at) are set to the current time.
+	Version string `yaml:"version" json:"version"`
+	// CreatedAt is the column set to the current time by creates, defaults to created_at. UpdatedAt is the column
+	// set to the current time by creates and updates, defaults to updated_at. Both are omitted from the inputs of
+	// mutations, - disables them.
+	CreatedAt string `yaml:"createdAt" json:"createdAt"`
+	UpdatedAt string `yaml:"updatedAt" json:"updatedAt"`
+	// DeletedAt is the column of soft deletes, which set it to the current time instead of deleting rows, defaults
+	// to deleted_at. Soft-deleted rows are excluded from all reads, - disables soft deletes.
+	DeletedAt string `yaml:"deletedAt" json:"deletedAt"`
+}
+
+// Column contains the configuration of a column.
+type Column struct {
+	// Name overrides the name of the field generated from the column.
+	Name string `yaml:"name" json:"name"`
+	// BackReference overrides the name of the back-reference field generated from a foreign key column.
+	BackReference string `yaml:"backReference" json:"backReference"`
+	// Hidden removes the column from the schema. Unique keys containing the column are dropped entirely, since the
+	// remaining columns are not unique by themselves.
+	Hidden bool `yaml:"hidden" json:"hidden"`
+	// Type forces the scalar type of the field (Int, Float, String, Boolean or DateTime).
+	Type string `yaml:"type" json:"type"`
+	// Roles restrict reading and writing the field to the listed roles.
+	Roles []string `yaml:"roles" json:"roles"`
+}
+
+// Load reads a configuration file. Files with the extension .json are parsed as JSON, all others as YAML. An
+// empty path results in an empty configuration.
+func Load(path string) (*Config, error) {
+	c := &Config{}
+	if path == "" {
+		return c, nil
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read configuration file '%s'", path)
+	}
+
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		// unknown keys are rejected like in YAML
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(c)
+	} else {
+		err = yaml.UnmarshalStrict(content, c)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse configuration file '%s'", path)
+	}
+
+	return c, nil
+}
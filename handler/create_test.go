@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const createSchema = `
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL REFERENCES users(id), title TEXT NOT NULL CHECK (title <> ''));
+CREATE TABLE profiles (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL UNIQUE REFERENCES users(id), bio TEXT NOT NULL);
+CREATE TABLE tags (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+CREATE TABLE post_tags (
+	post_id INTEGER NOT NULL REFERENCES posts(id),
+	tag_id INTEGER NOT NULL REFERENCES tags(id),
+	PRIMARY KEY (post_id, tag_id)
+);
+INSERT INTO tags (name) VALUES ('a'), ('b');
+`
+
+func TestNestedCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		message string
+		checks  map[string]int
+	}{
+		{
+			name:  "without nested inputs",
+			input: `{clientMutationId: "1", name: "u"}`,
+			checks: map[string]int{
+				"SELECT COUNT(*) FROM users WHERE id = 1 AND name = 'u'": 1,
+				"SELECT COUNT(*) FROM posts":                             0,
+				"SELECT COUNT(*) FROM profiles":                          0,
+			},
+		},
+		{
+			name:  "one-to-one back-reference",
+			input: `{clientMutationId: "1", name: "u", profile: {bio: "b"}}`,
+			checks: map[string]int{
+				"SELECT COUNT(*) FROM users":                                    1,
+				"SELECT COUNT(*) FROM profiles WHERE user_id = 1 AND bio = 'b'": 1,
+			},
+		},
+		{
+			name: "back-references and joined references",
+			input: fmt.Sprintf(`{clientMutationId: "1", name: "u", posts: [{title: "p1", tagIds: ["%s", "%s"]}, {title: "p2"}]}`,
+				testID("Tag", 1), testID("Tag", 2)),
+			checks: map[string]int{
+				"SELECT COUNT(*) FROM users":                                       1,
+				"SELECT COUNT(*) FROM posts WHERE user_id = 1":                     2,
+				"SELECT COUNT(*) FROM post_tags WHERE post_id = 1 AND tag_id <= 2": 2,
+				"SELECT COUNT(*) FROM post_tags":                                   2,
+			},
+		},
+		{
+			name:    "invalid nested input",
+			input:   `{clientMutationId: "1", name: "u", posts: [{title: "p1"}, {title: ""}]}`,
+			message: "check constraint posts failed",
+			checks: map[string]int{
+				"SELECT COUNT(*) FROM users": 0,
+				"SELECT COUNT(*) FROM posts": 0,
+			},
+		},
+		{
+			name:    "joined reference of another object",
+			input:   fmt.Sprintf(`{clientMutationId: "1", name: "u", posts: [{title: "p1", tagIds: ["%s"]}]}`, testID("User", 1)),
+			message: "unexpected id type User",
+			checks: map[string]int{
+				"SELECT COUNT(*) FROM users":     0,
+				"SELECT COUNT(*) FROM post_tags": 0,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestServer(t, createSchema, "")
+			defer s.Close()
+
+			response := s.do("", `mutation { createUser(input: `+test.input+`) { user { id } } }`)
+			switch messages := strings.Join(response.errorMessages(), "; "); {
+			case test.message == "" && messages != "":
+				t.Errorf("unexpected errors %s", messages)
+			case !strings.Contains(messages, test.message):
+				t.Errorf("expected an error containing %q, got %q", test.message, messages)
+			}
+			for check, count := range test.checks {
+				if actual := s.count(check); actual != count {
+					t.Errorf("%s returned %d, expected %d", check, actual, count)
+				}
+			}
+		})
+	}
+}
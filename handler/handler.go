@@ -3,39 +3,202 @@ package handler
 import (
 	"context"
 	"database/sql"
+	"dynamic-graphql-api/handler/auth"
+	"dynamic-graphql-api/handler/complexity"
+	"dynamic-graphql-api/handler/config"
 	"dynamic-graphql-api/handler/schema"
 	"dynamic-graphql-api/handler/schema/db"
+	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
 	"github.com/graphql-go/handler"
 	"github.com/pkg/errors"
 )
 
 // Handler implements the http.Handler interface and stores a database connection.
 type Handler struct {
-	db *sql.DB
-	h  *handler.Handler
+	db     *sql.DB
+	schema *graphql.Schema
+	h      *handler.Handler
+
+	authenticator auth.Authenticator
+	authRequired  bool
+
+	limits           config.Limits
+	persistedQueries *persistedQueries
+	rateLimiter      *rateLimiter
+
+	// pageSizes are the page sizes of the connections of objects, maxPageSize is set if any connection has a maximum.
+	pageSizes   map[string]complexity.PageSize
+	maxPageSize bool
 }
 
 // ServeHTTP provides an entrypoint into executing graphQL queries.
 func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	h.h.ContextHandler(context.WithValue(r.Context(), schema.KeyDB, h.db), w, r)
+	if isPlaygroundRequest(r) {
+		// the playground is rendered without executing the request
+		r = r.Clone(r.Context())
+		r.URL.RawQuery = ""
+		r.Body = http.NoBody
+		h.h.ContextHandler(r.Context(), w, r)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), schema.KeyDB, h.db)
+
+	var claims auth.Claims
+	if h.authenticator != nil {
+		var err error
+		claims, err = h.authenticator.Authenticate(r)
+		if err != nil {
+			writeUnauthorized(w, errors.Wrap(err, "invalid credentials"))
+			return
+		}
+		if claims == nil && h.authRequired {
+			writeUnauthorized(w, errors.New("missing credentials"))
+			return
+		}
+
+		ctx = auth.NewContext(ctx, claims)
+	}
+
+	opts, extensions := newRequestOptions(r)
+	if h.persistedQueries != nil {
+		if err := h.persistedQueries.resolve(opts, extensions); err != nil {
+			writeResult(w, http.StatusOK, &graphql.Result{Errors: []gqlerrors.FormattedError{formatError(err)}})
+			return
+		}
+	}
+	document := parseQuery(opts)
+	result, err := h.checkLimits(document, opts)
+	if err != nil {
+		writeResult(w, http.StatusOK, &graphql.Result{Errors: []gqlerrors.FormattedError{formatError(err)}})
+		return
+	}
+	operation := ast.OperationTypeQuery
+	if operationType(document, opts.OperationName) == ast.OperationTypeMutation {
+		operation = ast.OperationTypeMutation
+	}
+	if h.rateLimiter != nil {
+		if err := h.rateLimiter.take(h.rateLimiter.clientKey(r, claims), operation, result.Cost); err != nil {
+			if rateLimitErr, ok := err.(RateLimitError); ok {
+				w.Header().Set("Retry-After", strconv.Itoa(rateLimitErr.retryAfterSeconds()))
+			}
+			writeResult(w, http.StatusTooManyRequests, &graphql.Result{Errors: []gqlerrors.FormattedError{formatError(err)}})
+			return
+		}
+	}
+
+	params := graphql.Params{
+		Schema:         *h.schema,
+		RequestString:  opts.Query,
+		VariableValues: opts.Variables,
+		OperationName:  opts.OperationName,
+		Context:        ctx,
+	}
+	if operation == ast.OperationTypeMutation && (extensions.Transaction == nil || *extensions.Transaction) {
+		writeResult(w, http.StatusOK, h.executeInTransaction(ctx, params))
+		return
+	}
+
+	writeResult(w, http.StatusOK, graphql.Do(params))
+}
+
+// SetAuthenticator replaces the authenticator created from the configuration (e.g. by a custom implementation).
+func (h *Handler) SetAuthenticator(a auth.Authenticator, required bool) {
+	h.authenticator = a
+	h.authRequired = required
+}
+
+func isPlaygroundRequest(r *http.Request) bool {
+	acceptHeader := r.Header.Get("Accept")
+	_, raw := r.URL.Query()["raw"]
+	return !raw && !strings.Contains(acceptHeader, "application/json") && strings.Contains(acceptHeader, "text/html")
+}
+
+// formatError formats errors rejecting requests before their execution including their extensions.
+func formatError(err error) gqlerrors.FormattedError {
+	formatted := gqlerrors.FormatError(err)
+	if extended, ok := err.(gqlerrors.ExtendedError); ok {
+		formatted.Extensions = extended.Extensions()
+	}
+
+	return formatted
+}
+
+func writeResult(w http.ResponseWriter, statusCode int, result *graphql.Result) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+
+	buff, _ := json.MarshalIndent(result, "", "\t")
+	w.Write(buff)
 }
 
-// NewHandler creates a new GraphQL handler with a database connection.
-func NewHandler(driverName string, dataSourceName string) (*Handler, error) {
-	db, sqls, err := db.NewDB(driverName, dataSourceName)
+func writeUnauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	writeResult(w, http.StatusUnauthorized, &graphql.Result{Errors: []gqlerrors.FormattedError{formatError(err)}})
+}
+
+// NewHandler creates a new GraphQL handler with a database connection. The configuration file at configPath is
+// optional, an empty path results in the default configuration.
+func NewHandler(driverName string, dataSourceName string, configPath string) (*Handler, error) {
+	c, err := config.Load(configPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load configuration")
+	}
+
+	db, sqls, uniqueIndexes, err := db.NewDB(driverName, dataSourceName)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create database")
 	}
 
-	s, err := schema.NewSchema(sqls)
+	s, err := schema.NewSchema(sqls, uniqueIndexes, c)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create schema")
 	}
 
+	var authenticator auth.Authenticator
+	if c.Auth.JWT != nil {
+		authenticator, err = auth.NewJWTVerifier(*c.Auth.JWT)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create authentication")
+		}
+	} else if c.Auth.Required {
+		return nil, errors.New("required authentication is not configured")
+	}
+
+	persistedQueries, err := newPersistedQueries(c.PersistedQueries)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create persisted queries")
+	}
+
+	rateLimiter, err := newRateLimiter(c.RateLimit)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create rate limiting")
+	}
+
+	pageSizes := map[string]complexity.PageSize{}
+	var maxPageSize bool
+	for objName, pageSize := range schema.PageSizes() {
+		pageSizes[objName] = complexity.PageSize{Default: int(pageSize.Default), Max: int(pageSize.Max)}
+		maxPageSize = maxPageSize || pageSize.Max > 0
+	}
+
 	return &Handler{
-		db: db,
+		authenticator:    authenticator,
+		authRequired:     c.Auth.Required,
+		limits:           c.Limits,
+		pageSizes:        pageSizes,
+		maxPageSize:      maxPageSize,
+		persistedQueries: persistedQueries,
+		rateLimiter:      rateLimiter,
+		db:               db,
+		schema:           s,
 		h: handler.New(&handler.Config{
 			Schema:     s,
 			Pretty:     true,
@@ -46,5 +209,11 @@ func NewHandler(driverName string, dataSourceName string) (*Handler, error) {
 
 // Close closes the database connection.
 func (h *Handler) Close() error {
+	if h.persistedQueries != nil {
+		if store, ok := h.persistedQueries.store.(*SQLiteQueryStore); ok {
+			store.Close()
+		}
+	}
+
 	return h.db.Close()
 }
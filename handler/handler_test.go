@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// testSecret signs the tokens of testToken, configurations of tests enable it with testJWTConfig.
+const testSecret = "secret"
+
+const testJWTConfig = `
+auth:
+  jwt:
+    algorithm: HS256
+    secret: secret
+`
+
+// testServer is a handler of a temporary SQLite database.
+type testServer struct {
+	t   *testing.T
+	dir string
+	h   *Handler
+	// db is a separate connection for preparing and checking rows.
+	db *sql.DB
+}
+
+// newTestServer creates the database from the SQL statements and the handler with the YAML configuration. The
+// server must be closed.
+func newTestServer(t *testing.T, statements string, configuration string) *testServer {
+	dir, err := ioutil.TempDir("", "handler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{t: t, dir: dir}
+
+	path := filepath.Join(dir, "test.db")
+	s.db, err = sql.Open("sqlite3", path)
+	if err != nil {
+		s.Close()
+		t.Fatal(err)
+	}
+	if _, err := s.db.Exec(statements); err != nil {
+		s.Close()
+		t.Fatal(err)
+	}
+
+	configPath := filepath.Join(dir, "config.yml")
+	if err := ioutil.WriteFile(configPath, []byte(configuration), 0600); err != nil {
+		s.Close()
+		t.Fatal(err)
+	}
+
+	s.h, err = NewHandler("sqlite3", path, configPath)
+	if err != nil {
+		s.Close()
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+// Close closes the databases and removes them.
+func (s *testServer) Close() {
+	if s.h != nil {
+		s.h.db.Close()
+	}
+	if s.db != nil {
+		s.db.Close()
+	}
+	os.RemoveAll(s.dir)
+}
+
+type testResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+// hasErrorCode returns whether an error of the response has the code.
+func (r testResponse) hasErrorCode(code string) bool {
+	for _, err := range r.Errors {
+		if err.Extensions["code"] == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// errorMessages returns the messages of the errors of the response.
+func (r testResponse) errorMessages() []string {
+	var messages []string
+	for _, err := range r.Errors {
+		messages = append(messages, err.Message)
+	}
+
+	return messages
+}
+
+// do executes the query with the token (if not empty) and returns the decoded response.
+func (s *testServer) do(token string, query string) testResponse {
+	return s.post(token, map[string]interface{}{"query": query})
+}
+
+// post sends the GraphQL request (e.g. with extensions) with the token (if not empty) and returns the decoded
+// response.
+func (s *testServer) post(token string, request map[string]interface{}) testResponse {
+	body, err := json.Marshal(request)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.h.ServeHTTP(w, r)
+
+	var response testResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		s.t.Fatalf("invalid response %s: %v", w.Body.String(), err)
+	}
+
+	return response
+}
+
+// count returns the result of a COUNT(*) query.
+func (s *testServer) count(query string, args ...interface{}) int {
+	var count int
+	if err := s.db.QueryRow(query, args...).Scan(&count); err != nil {
+		s.t.Fatal(err)
+	}
+
+	return count
+}
+
+// testToken returns an HS256 token of the claims signed with testSecret.
+func testToken(t *testing.T, claims map[string]interface{}) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(signed))
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// testID returns the unprotected global ID of an object.
+func testID(object string, id int) string {
+	return base64.StdEncoding.EncodeToString([]byte(object + ":" + strconv.Itoa(id)))
+}
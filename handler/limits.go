@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"dynamic-graphql-api/handler/complexity"
+	"fmt"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/graphql-go/handler"
+)
+
+// LimitError is returned if an operation exceeds the configured limits.
+type LimitError struct {
+	Message string
+}
+
+func (e LimitError) Error() string {
+	return e.Message
+}
+
+// Extensions adds a machine readable code to the GraphQL error.
+func (e LimitError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code": "QUERY_TOO_COMPLEX",
+	}
+}
+
+// parseQuery parses the query of a request, syntax errors result in nil and are reported by the execution.
+func parseQuery(opts *handler.RequestOptions) *ast.Document {
+	document, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(opts.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return nil
+	}
+
+	return document
+}
+
+// checkLimits rejects operations exceeding the configured depth, cost or page sizes before they are executed. The
+// operation is only analyzed if limits are configured or its cost is needed for rate limiting.
+func (h Handler) checkLimits(document *ast.Document, opts *handler.RequestOptions) (complexity.Result, error) {
+	weighted := h.rateLimiter != nil && h.rateLimiter.weighted
+	if document == nil || (h.limits.MaxDepth <= 0 && h.limits.MaxCost <= 0 && !h.limits.RequirePageSize && !h.maxPageSize && !weighted) {
+		return complexity.Result{}, nil
+	}
+
+	result, err := complexity.Analyze(h.schema, document, opts.OperationName, opts.Variables, complexity.Options{
+		DefaultPageSize: 100,
+		PageSizes:       h.pageSizes,
+		RequirePageSize: h.limits.RequirePageSize,
+		DefaultDepth:    10,
+	})
+	if err != nil {
+		return result, LimitError{Message: err.Error()}
+	}
+
+	if h.limits.MaxDepth > 0 && result.Depth > h.limits.MaxDepth {
+		return result, LimitError{Message: fmt.Sprintf("query depth %d exceeds the maximum depth of %d", result.Depth, h.limits.MaxDepth)}
+	}
+	if h.limits.MaxCost > 0 && result.Cost > h.limits.MaxCost {
+		return result, LimitError{Message: fmt.Sprintf("query cost %d exceeds the maximum cost of %d", result.Cost, h.limits.MaxCost)}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,314 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"dynamic-graphql-api/handler/config"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/graphql-go/handler"
+	"github.com/pkg/errors"
+)
+
+// QueryStore stores persisted queries by their SHA-256 hash.
+type QueryStore interface {
+	// Get returns the query of the hash and whether it exists.
+	Get(hash string) (string, bool, error)
+	// Put stores the query of the hash.
+	Put(hash string, query string) error
+}
+
+// MemoryQueryStore stores persisted queries in memory. The oldest queries are evicted if the store is full.
+type MemoryQueryStore struct {
+	mutex      sync.Mutex
+	maxEntries int
+	queries    map[string]string
+	hashes     []string
+}
+
+// NewMemoryQueryStore creates an in-memory store of at most maxEntries queries.
+func NewMemoryQueryStore(maxEntries int) *MemoryQueryStore {
+	return &MemoryQueryStore{
+		maxEntries: maxEntries,
+		queries:    map[string]string{},
+	}
+}
+
+// Get returns the query of the hash and whether it exists.
+func (s *MemoryQueryStore) Get(hash string) (string, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	query, ok := s.queries[hash]
+	return query, ok, nil
+}
+
+// Put stores the query of the hash.
+func (s *MemoryQueryStore) Put(hash string, query string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.queries[hash]; ok {
+		return nil
+	}
+
+	if len(s.hashes) >= s.maxEntries {
+		delete(s.queries, s.hashes[0])
+		s.hashes = s.hashes[1:]
+	}
+	s.queries[hash] = query
+	s.hashes = append(s.hashes, hash)
+
+	return nil
+}
+
+// SQLiteQueryStore stores persisted queries in a separate SQLite database, so that they survive restarts.
+type SQLiteQueryStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteQueryStore opens (and creates) the SQLite database at path.
+func NewSQLiteQueryStore(path string) (*SQLiteQueryStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS persisted_queries (hash TEXT PRIMARY KEY, query TEXT NOT NULL)"); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteQueryStore{db: db}, nil
+}
+
+// Get returns the query of the hash and whether it exists.
+func (s *SQLiteQueryStore) Get(hash string) (string, bool, error) {
+	var query string
+	err := s.db.QueryRow("SELECT query FROM persisted_queries WHERE hash = ?", hash).Scan(&query)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	return query, true, nil
+}
+
+// Put stores the query of the hash.
+func (s *SQLiteQueryStore) Put(hash string, query string) error {
+	_, err := s.db.Exec("INSERT OR IGNORE INTO persisted_queries (hash, query) VALUES (?, ?)", hash, query)
+	return err
+}
+
+// Close closes the database.
+func (s *SQLiteQueryStore) Close() error {
+	return s.db.Close()
+}
+
+// PersistedQueryError is returned if a persisted query is unknown or not allowed.
+type PersistedQueryError struct {
+	Message string
+	Code    string
+}
+
+func (e PersistedQueryError) Error() string {
+	return e.Message
+}
+
+// Extensions adds a machine readable code to the GraphQL error.
+func (e PersistedQueryError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code": e.Code,
+	}
+}
+
+// persistedQueries resolves automatic persisted queries and restricts operations to the manifest.
+type persistedQueries struct {
+	store    QueryStore
+	manifest map[string]string
+	strict   bool
+}
+
+func newPersistedQueries(c config.PersistedQueries) (*persistedQueries, error) {
+	if !c.Enabled && !c.Strict {
+		return nil, nil
+	}
+
+	p := &persistedQueries{strict: c.Strict}
+
+	if c.Manifest != "" {
+		manifest, err := readManifest(c.Manifest)
+		if err != nil {
+			return nil, err
+		}
+		p.manifest = manifest
+	} else if c.Strict {
+		return nil, errors.New("strict persisted queries require a manifest")
+	}
+
+	// queries are only registered automatically if arbitrary queries are allowed
+	if c.Enabled && !c.Strict {
+		switch c.Store {
+		case "", "memory":
+			maxEntries := c.MaxEntries
+			if maxEntries <= 0 {
+				maxEntries = 1000
+			}
+			p.store = NewMemoryQueryStore(maxEntries)
+		case "sqlite":
+			if c.Path == "" {
+				return nil, errors.New("missing path of the persisted queries database")
+			}
+			store, err := NewSQLiteQueryStore(c.Path)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to open persisted queries database '%s'", c.Path)
+			}
+			p.store = store
+		default:
+			return nil, errors.Errorf("unsupported persisted queries store %s", c.Store)
+		}
+	}
+
+	return p, nil
+}
+
+// readManifest reads a JSON file mapping hashes to queries, either as object or in the format of Apollo's persisted
+// query manifest.
+func readManifest(path string) (map[string]string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read manifest '%s'", path)
+	}
+
+	var apolloManifest struct {
+		Operations []struct {
+			ID   string `json:"id"`
+			Body string `json:"body"`
+		} `json:"operations"`
+	}
+	var manifest map[string]string
+	if err := json.Unmarshal(content, &apolloManifest); err == nil && apolloManifest.Operations != nil {
+		manifest = map[string]string{}
+		for _, operation := range apolloManifest.Operations {
+			manifest[operation.ID] = operation.Body
+		}
+	} else if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse manifest '%s'", path)
+	}
+
+	// queries are looked up by the hash sent by clients, so a wrong hash would allow a different query
+	for hash, query := range manifest {
+		if hashQuery(query) != hash {
+			return nil, errors.Errorf("hash %s in manifest '%s' does not match the SHA-256 hash of its query", hash, path)
+		}
+	}
+
+	return manifest, nil
+}
+
+func hashQuery(query string) string {
+	hash := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(hash[:])
+}
+
+// requestExtensions contains the extensions of a GraphQL request.
+type requestExtensions struct {
+	PersistedQuery *struct {
+		Version    int    `json:"version"`
+		SHA256Hash string `json:"sha256Hash"`
+	} `json:"persistedQuery"`
+	// Transaction disables the transaction of mutation operations if false.
+	Transaction *bool `json:"transaction"`
+}
+
+// newRequestOptions parses the GraphQL request options and the extensions of a request.
+func newRequestOptions(r *http.Request) (*handler.RequestOptions, requestExtensions) {
+	var extensions requestExtensions
+
+	values := r.URL.Query()
+	if values.Get("extensions") != "" {
+		json.Unmarshal([]byte(values.Get("extensions")), &extensions)
+	} else if r.Method == http.MethodPost && r.Body != nil {
+		body, _ := ioutil.ReadAll(r.Body)
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		var request struct {
+			Extensions requestExtensions `json:"extensions"`
+		}
+		json.Unmarshal(body, &request)
+		extensions = request.Extensions
+	}
+
+	opts := handler.NewRequestOptions(r)
+	if opts.Query == "" && values.Get("query") == "" {
+		// requests of persisted queries by GET do not contain the query
+		if values.Get("variables") != "" {
+			json.Unmarshal([]byte(values.Get("variables")), &opts.Variables)
+		}
+		if values.Get("operationName") != "" {
+			opts.OperationName = values.Get("operationName")
+		}
+	}
+
+	return opts, extensions
+}
+
+// resolve replaces the hash of persisted queries by the query and registers new queries. In strict mode only queries
+// of the manifest are accepted.
+func (p *persistedQueries) resolve(opts *handler.RequestOptions, extensions requestExtensions) error {
+	var hash string
+	if extensions.PersistedQuery != nil {
+		hash = extensions.PersistedQuery.SHA256Hash
+	}
+
+	if hash == "" {
+		if p.strict {
+			if _, ok := p.manifest[hashQuery(opts.Query)]; !ok {
+				return PersistedQueryError{Message: "operation is not allowed", Code: "OPERATION_NOT_ALLOWED"}
+			}
+		}
+
+		return nil
+	}
+
+	if opts.Query != "" {
+		if hashQuery(opts.Query) != hash {
+			return PersistedQueryError{Message: "provided sha does not match query", Code: "PERSISTED_QUERY_HASH_MISMATCH"}
+		}
+
+		if _, ok := p.manifest[hash]; ok {
+			return nil
+		}
+		if p.strict {
+			return PersistedQueryError{Message: "operation is not allowed", Code: "OPERATION_NOT_ALLOWED"}
+		}
+
+		return p.store.Put(hash, opts.Query)
+	}
+
+	if query, ok := p.manifest[hash]; ok {
+		opts.Query = query
+		return nil
+	}
+	if p.strict {
+		return PersistedQueryError{Message: "operation is not allowed", Code: "OPERATION_NOT_ALLOWED"}
+	}
+
+	query, ok, err := p.store.Get(hash)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return PersistedQueryError{Message: "PersistedQueryNotFound", Code: "PERSISTED_QUERY_NOT_FOUND"}
+	}
+	opts.Query = query
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+const policySchema = `
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE tasks (id INTEGER PRIMARY KEY, owner_id INTEGER REFERENCES users(id), title TEXT);
+INSERT INTO users (name) VALUES ('a'), ('b');
+INSERT INTO tasks (owner_id, title) VALUES (1, 'a1'), (1, 'a2'), (2, 'b1');
+`
+
+const policyConfig = testJWTConfig + `
+tables:
+  tasks:
+    policies:
+      read: owner_id = $claims.sub
+      update: owner_id = $claims.sub
+      delete: owner_id = $claims.sub AND $claims.role = 'admin'
+`
+
+// titles returns the titles of the tasks of a tasks connection.
+func titles(t *testing.T, response testResponse) []string {
+	if len(response.Errors) > 0 {
+		t.Fatalf("unexpected errors %v", response.errorMessages())
+	}
+
+	titles := []string{}
+	for _, edge := range response.Data["tasks"].(map[string]interface{})["edges"].([]interface{}) {
+		titles = append(titles, edge.(map[string]interface{})["node"].(map[string]interface{})["title"].(string))
+	}
+
+	return titles
+}
+
+func TestPolicyRead(t *testing.T) {
+	s := newTestServer(t, policySchema, policyConfig)
+	defer s.Close()
+
+	query := `{ tasks { edges { node { title } } } }`
+
+	if got := titles(t, s.do(testToken(t, map[string]interface{}{"sub": 1}), query)); !reflect.DeepEqual(got, []string{"a1", "a2"}) {
+		t.Errorf("owner 1 read %v", got)
+	}
+	if got := titles(t, s.do(testToken(t, map[string]interface{}{"sub": 2}), query)); !reflect.DeepEqual(got, []string{"b1"}) {
+		t.Errorf("owner 2 read %v", got)
+	}
+	// the missing claim is NULL, which matches no rows
+	if got := titles(t, s.do("", query)); len(got) != 0 {
+		t.Errorf("unauthenticated caller read %v", got)
+	}
+
+	response := s.do(testToken(t, map[string]interface{}{"sub": 2}), fmt.Sprintf(`{ node(id: "%s") { id } }`, testID("Task", 1)))
+	if len(response.Errors) > 0 || response.Data["node"] != nil {
+		t.Errorf("owner 2 read the task of owner 1: %v %v", response.Data, response.errorMessages())
+	}
+}
+
+func TestPolicyUpdateAndDelete(t *testing.T) {
+	s := newTestServer(t, policySchema, policyConfig)
+	defer s.Close()
+
+	owner := testToken(t, map[string]interface{}{"sub": 1})
+	admin := testToken(t, map[string]interface{}{"sub": 1, "role": "admin"})
+	other := testToken(t, map[string]interface{}{"sub": 2, "role": "admin"})
+
+	tests := []struct {
+		name   string
+		token  string
+		query  string
+		failed bool
+		check  string
+		count  int
+	}{
+		{
+			name:   "update of another owner",
+			token:  other,
+			query:  fmt.Sprintf(`mutation { updateTask(input: {clientMutationId: "x", id: "%s", title: "changed"}) { clientMutationId } }`, testID("Task", 1)),
+			failed: true,
+			check:  "SELECT COUNT(*) FROM tasks WHERE title = 'changed'",
+			count:  0,
+		},
+		{
+			name:  "update of the owner",
+			token: owner,
+			query: fmt.Sprintf(`mutation { updateTask(input: {clientMutationId: "x", id: "%s", title: "changed"}) { clientMutationId } }`, testID("Task", 1)),
+			check: "SELECT COUNT(*) FROM tasks WHERE id = 1 AND title = 'changed'",
+			count: 1,
+		},
+		{
+			name:   "delete without role",
+			token:  owner,
+			query:  fmt.Sprintf(`mutation { deleteTask(input: {clientMutationId: "x", id: "%s"}) { clientMutationId } }`, testID("Task", 2)),
+			failed: true,
+			check:  "SELECT COUNT(*) FROM tasks WHERE id = 2",
+			count:  1,
+		},
+		{
+			name:   "delete of another owner",
+			token:  other,
+			query:  fmt.Sprintf(`mutation { deleteTask(input: {clientMutationId: "x", id: "%s"}) { clientMutationId } }`, testID("Task", 2)),
+			failed: true,
+			check:  "SELECT COUNT(*) FROM tasks WHERE id = 2",
+			count:  1,
+		},
+		{
+			name:  "delete of the owner with role",
+			token: admin,
+			query: fmt.Sprintf(`mutation { deleteTask(input: {clientMutationId: "x", id: "%s"}) { clientMutationId } }`, testID("Task", 2)),
+			check: "SELECT COUNT(*) FROM tasks WHERE id = 2",
+			count: 0,
+		},
+	}
+
+	// the cases depend on each other
+	for _, test := range tests {
+		response := s.do(test.token, test.query)
+		if failed := len(response.Errors) > 0; failed != test.failed {
+			t.Errorf("%s: unexpected errors %v", test.name, response.errorMessages())
+		}
+		if count := s.count(test.check); count != test.count {
+			t.Errorf("%s: %s returned %d, expected %d", test.name, test.check, count, test.count)
+		}
+	}
+}
+
+// Forward references to hidden rows resolve to null instead of failing the referencing object.
+func TestPolicyHiddenReference(t *testing.T) {
+	s := newTestServer(t, `
+		CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+		CREATE TABLE projects (id INTEGER PRIMARY KEY, name TEXT, deleted_at DATETIME);
+		CREATE TABLE notes (
+			id INTEGER PRIMARY KEY,
+			author_id INTEGER NOT NULL REFERENCES users(id),
+			project_id INTEGER NOT NULL REFERENCES projects(id),
+			title TEXT
+		);
+		INSERT INTO users (name) VALUES ('a'), ('b');
+		INSERT INTO projects (name, deleted_at) VALUES ('active', NULL), ('deleted', '2020-01-01 00:00:00');
+		INSERT INTO notes (author_id, project_id, title) VALUES (1, 1, 'n1'), (2, 2, 'n2');
+	`, testJWTConfig+`
+tables:
+  users:
+    policies:
+      read: id = $claims.sub
+`)
+	defer s.Close()
+
+	response := s.do(testToken(t, map[string]interface{}{"sub": 1}), `{ notes { edges { node { title author { name } project { name } } } } }`)
+	if len(response.Errors) > 0 {
+		t.Fatalf("unexpected errors %v", response.errorMessages())
+	}
+
+	expected := []interface{}{
+		map[string]interface{}{"title": "n1", "author": map[string]interface{}{"name": "a"}, "project": map[string]interface{}{"name": "active"}},
+		map[string]interface{}{"title": "n2", "author": nil, "project": nil},
+	}
+	var nodes []interface{}
+	for _, edge := range response.Data["notes"].(map[string]interface{})["edges"].([]interface{}) {
+		nodes = append(nodes, edge.(map[string]interface{})["node"])
+	}
+	if !reflect.DeepEqual(nodes, expected) {
+		t.Errorf("expected %v, got %v", expected, nodes)
+	}
+}
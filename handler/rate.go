@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"dynamic-graphql-api/handler/auth"
+	"dynamic-graphql-api/handler/config"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/pkg/errors"
+)
+
+// RateLimitError is returned if a client exceeded its budget of operations.
+type RateLimitError struct {
+	Operation  string
+	RetryAfter time.Duration
+}
+
+func (e RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit of %s exceeded, retry after %d seconds", e.Operation, e.retryAfterSeconds())
+}
+
+// Extensions adds a machine readable code and the seconds to wait to the GraphQL error.
+func (e RateLimitError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":       "RATE_LIMITED",
+		"retryAfter": e.retryAfterSeconds(),
+	}
+}
+
+func (e RateLimitError) retryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// bucket is a token bucket, which is refilled lazily when tokens are taken.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// budget contains the buckets of all clients for one kind of operation.
+type budget struct {
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+}
+
+// rateLimiter limits queries and mutations per client.
+type rateLimiter struct {
+	mutex      sync.Mutex
+	budgets    map[string]*budget
+	weighted   bool
+	keyClaim   string
+	trustProxy bool
+	lastSweep  time.Time
+	now        func() time.Time
+}
+
+// sweepInterval is the interval of removing the buckets of idle clients.
+const sweepInterval = time.Minute
+
+func newRateLimiter(c config.RateLimit) (*rateLimiter, error) {
+	l := &rateLimiter{
+		budgets:    map[string]*budget{},
+		weighted:   c.Weighted,
+		keyClaim:   c.KeyClaim,
+		trustProxy: c.TrustProxy,
+		now:        time.Now,
+	}
+	if l.keyClaim == "" {
+		l.keyClaim = "sub"
+	}
+
+	for operation, b := range map[string]config.Budget{"query": c.Queries, "mutation": c.Mutations} {
+		if b.Rate < 0 || b.Burst < 0 {
+			return nil, errors.Errorf("negative rate limit of %s", operation)
+		}
+		if b.Rate == 0 {
+			continue
+		}
+
+		burst := float64(b.Burst)
+		if burst == 0 {
+			burst = math.Max(math.Ceil(b.Rate), 1)
+		}
+		l.budgets[operation] = &budget{
+			rate:    b.Rate,
+			burst:   burst,
+			buckets: map[string]*bucket{},
+		}
+	}
+
+	if len(l.budgets) == 0 {
+		return nil, nil
+	}
+
+	return l, nil
+}
+
+// clientKey identifies the client by the key claim of authenticated callers or by its IP.
+func (l *rateLimiter) clientKey(r *http.Request, claims auth.Claims) string {
+	if value, ok := claims.Value(l.keyClaim); ok && value != nil {
+		return fmt.Sprintf("claim:%v", value)
+	}
+
+	if l.trustProxy {
+		if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
+			return "ip:" + strings.TrimSpace(strings.Split(forwardedFor, ",")[0])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// take consumes the tokens of an operation (query or mutation) of the client. Operations costing more than the burst
+// consume the whole burst.
+func (l *rateLimiter) take(key string, operation string, cost int) error {
+	b, ok := l.budgets[operation]
+	if !ok {
+		return nil
+	}
+
+	tokens := 1.0
+	if l.weighted && cost > 1 {
+		tokens = math.Min(float64(cost), b.burst)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) > sweepInterval {
+		l.sweep(now)
+	}
+
+	bk, ok := b.buckets[key]
+	if !ok {
+		bk = &bucket{tokens: b.burst, last: now}
+		b.buckets[key] = bk
+	}
+	b.refill(bk, now)
+
+	if bk.tokens < tokens {
+		return RateLimitError{
+			Operation:  operation,
+			RetryAfter: time.Duration((tokens - bk.tokens) / b.rate * float64(time.Second)),
+		}
+	}
+	bk.tokens -= tokens
+
+	return nil
+}
+
+func (b *budget) refill(bk *bucket, now time.Time) {
+	bk.tokens = math.Min(b.burst, bk.tokens+now.Sub(bk.last).Seconds()*b.rate)
+	bk.last = now
+}
+
+// sweep removes full buckets, which behave like new buckets.
+func (l *rateLimiter) sweep(now time.Time) {
+	for _, b := range l.budgets {
+		for key, bk := range b.buckets {
+			b.refill(bk, now)
+			if bk.tokens >= b.burst {
+				delete(b.buckets, key)
+			}
+		}
+	}
+	l.lastSweep = now
+}
+
+// operationType returns the type of the operation of the document (query, mutation or subscription), invalid
+// documents are treated as queries.
+func operationType(document *ast.Document, operationName string) string {
+	if document == nil {
+		return ast.OperationTypeQuery
+	}
+
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" || (operation.Name != nil && operation.Name.Value == operationName) {
+			return operation.Operation
+		}
+	}
+
+	return ast.OperationTypeQuery
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"dynamic-graphql-api/handler/auth"
+	"dynamic-graphql-api/handler/config"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterTake(t *testing.T) {
+	// step takes the tokens of an operation after advancing the clock
+	type step struct {
+		advance    time.Duration
+		key        string
+		operation  string
+		cost       int
+		allowed    bool
+		retryAfter int
+	}
+
+	tests := []struct {
+		name  string
+		limit config.RateLimit
+		steps []step
+	}{
+		{
+			name:  "burst",
+			limit: config.RateLimit{Queries: config.Budget{Rate: 1, Burst: 2}},
+			steps: []step{
+				{0, "a", "query", 1, true, 0},
+				{0, "a", "query", 1, true, 0},
+				{0, "a", "query", 1, false, 1},
+			},
+		},
+		{
+			name:  "default burst",
+			limit: config.RateLimit{Queries: config.Budget{Rate: 0.5}},
+			steps: []step{
+				{0, "a", "query", 1, true, 0},
+				{0, "a", "query", 1, false, 2},
+			},
+		},
+		{
+			name:  "refill",
+			limit: config.RateLimit{Queries: config.Budget{Rate: 2, Burst: 2}},
+			steps: []step{
+				{0, "a", "query", 1, true, 0},
+				{0, "a", "query", 1, true, 0},
+				{0, "a", "query", 1, false, 1},
+				{500 * time.Millisecond, "a", "query", 1, true, 0},
+				{0, "a", "query", 1, false, 1},
+				// buckets are not filled beyond their burst
+				{time.Hour, "a", "query", 1, true, 0},
+				{0, "a", "query", 1, true, 0},
+				{0, "a", "query", 1, false, 1},
+			},
+		},
+		{
+			name:  "clients",
+			limit: config.RateLimit{Queries: config.Budget{Rate: 1, Burst: 1}},
+			steps: []step{
+				{0, "a", "query", 1, true, 0},
+				{0, "a", "query", 1, false, 1},
+				{0, "b", "query", 1, true, 0},
+			},
+		},
+		{
+			name:  "operations",
+			limit: config.RateLimit{Queries: config.Budget{Rate: 1, Burst: 1}, Mutations: config.Budget{Rate: 1, Burst: 1}},
+			steps: []step{
+				{0, "a", "query", 1, true, 0},
+				{0, "a", "mutation", 1, true, 0},
+				{0, "a", "mutation", 1, false, 1},
+			},
+		},
+		{
+			name:  "unlimited operation",
+			limit: config.RateLimit{Mutations: config.Budget{Rate: 1, Burst: 1}},
+			steps: []step{
+				{0, "a", "query", 1, true, 0},
+				{0, "a", "query", 1, true, 0},
+			},
+		},
+		{
+			name:  "unweighted",
+			limit: config.RateLimit{Queries: config.Budget{Rate: 1, Burst: 10}},
+			steps: []step{
+				{0, "a", "query", 100, true, 0},
+				{0, "a", "query", 100, true, 0},
+			},
+		},
+		{
+			name:  "weighted",
+			limit: config.RateLimit{Queries: config.Budget{Rate: 1, Burst: 10}, Weighted: true},
+			steps: []step{
+				{0, "a", "query", 6, true, 0},
+				{0, "a", "query", 6, false, 2},
+				{2 * time.Second, "a", "query", 6, true, 0},
+			},
+		},
+		{
+			// operations costing more than the burst consume the whole burst
+			name:  "weighted beyond burst",
+			limit: config.RateLimit{Queries: config.Budget{Rate: 1, Burst: 10}, Weighted: true},
+			steps: []step{
+				{0, "a", "query", 1000, true, 0},
+				{0, "a", "query", 1, false, 1},
+				{10 * time.Second, "a", "query", 1000, true, 0},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l, err := newRateLimiter(test.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			now := time.Unix(0, 0)
+			l.now = func() time.Time { return now }
+
+			for i, s := range test.steps {
+				now = now.Add(s.advance)
+
+				err := l.take(s.key, s.operation, s.cost)
+				if s.allowed {
+					if err != nil {
+						t.Fatalf("step %d: unexpected error %v", i, err)
+					}
+					continue
+				}
+
+				rateLimitErr, ok := err.(RateLimitError)
+				if !ok {
+					t.Fatalf("step %d: expected rate limit error, got %v", i, err)
+				}
+				if rateLimitErr.retryAfterSeconds() != s.retryAfter {
+					t.Fatalf("step %d: expected retry after %d seconds, got %d", i, s.retryAfter, rateLimitErr.retryAfterSeconds())
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	l, err := newRateLimiter(config.RateLimit{Queries: config.Budget{Rate: 1, Burst: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(0, 0)
+	l.now = func() time.Time { return now }
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		buckets []string
+	}{
+		{"new buckets", 0, []string{"a", "b"}},
+		{"before the interval", sweepInterval / 2, []string{"a", "b"}},
+		{"idle buckets", 2 * sweepInterval, []string{"b"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			now = now.Add(test.advance)
+			// a is only used once, b is used before every sweep
+			if test.advance == 0 {
+				if err := l.take("a", "query", 1); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := l.take("b", "query", 1); err != nil {
+				t.Fatal(err)
+			}
+
+			buckets := l.budgets["query"].buckets
+			if len(buckets) != len(test.buckets) {
+				t.Fatalf("expected buckets %v, got %v", test.buckets, buckets)
+			}
+			for _, key := range test.buckets {
+				if _, ok := buckets[key]; !ok {
+					t.Fatalf("expected buckets %v, got %v", test.buckets, buckets)
+				}
+			}
+		})
+	}
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	if l, err := newRateLimiter(config.RateLimit{}); l != nil || err != nil {
+		t.Errorf("expected no rate limiter without budgets, got %v %v", l, err)
+	}
+	if _, err := newRateLimiter(config.RateLimit{Queries: config.Budget{Rate: -1}}); err == nil {
+		t.Error("accepted a negative rate")
+	}
+	if _, err := newRateLimiter(config.RateLimit{Mutations: config.Budget{Rate: 1, Burst: -1}}); err == nil {
+		t.Error("accepted a negative burst")
+	}
+}
+
+func TestRateLimiterClientKey(t *testing.T) {
+	tests := []struct {
+		name         string
+		limit        config.RateLimit
+		claims       auth.Claims
+		remoteAddr   string
+		forwardedFor string
+		expected     string
+	}{
+		{"ip", config.RateLimit{}, nil, "10.0.0.1:1234", "", "ip:10.0.0.1"},
+		{"ip without port", config.RateLimit{}, nil, "10.0.0.1", "", "ip:10.0.0.1"},
+		{"sub claim", config.RateLimit{}, auth.Claims{"sub": "1"}, "10.0.0.1:1234", "", "claim:1"},
+		{"key claim", config.RateLimit{KeyClaim: "app.tenant"}, auth.Claims{"sub": "1", "app": map[string]interface{}{"tenant": "t"}}, "10.0.0.1:1234", "", "claim:t"},
+		{"missing key claim", config.RateLimit{KeyClaim: "tenant"}, auth.Claims{"sub": "1"}, "10.0.0.1:1234", "", "ip:10.0.0.1"},
+		{"untrusted proxy", config.RateLimit{}, nil, "10.0.0.1:1234", "192.168.0.1", "ip:10.0.0.1"},
+		{"trusted proxy", config.RateLimit{TrustProxy: true}, nil, "10.0.0.1:1234", "192.168.0.1, 10.0.0.2", "ip:192.168.0.1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.limit.Queries = config.Budget{Rate: 1}
+			l, err := newRateLimiter(test.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := http.NewRequest(http.MethodGet, "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.RemoteAddr = test.remoteAddr
+			if test.forwardedFor != "" {
+				r.Header.Set("X-Forwarded-For", test.forwardedFor)
+			}
+
+			if key := l.clientKey(r, test.claims); key != test.expected {
+				t.Fatalf("expected %s, got %s", test.expected, key)
+			}
+		})
+	}
+}
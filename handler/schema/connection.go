@@ -1,6 +1,9 @@
 package schema
 
 import (
+	"dynamic-graphql-api/handler/config"
+	"dynamic-graphql-api/handler/schema/db"
+	"dynamic-graphql-api/handler/schema/graph"
 	"fmt"
 
 	"github.com/graphql-go/graphql"
@@ -16,6 +19,30 @@ type connection struct {
 
 	// Edges
 	edges []cursor
+
+	// Owner of joined connections, identifies the rows of the join table together with the edges
+	owner cursor
+}
+
+// newConnection converts a result of a pagination query to a connection of the given object.
+func newConnection(objName string, result db.PaginationResult, includeDeleted bool) connection {
+	var conn connection
+	for _, id := range result.IDs {
+		conn.edges = append(conn.edges, cursor{object: objName, id: id, includeDeleted: includeDeleted})
+	}
+
+	if len(conn.edges) > 0 {
+		conn.startCursor = conn.edges[0]
+	}
+
+	if len(conn.edges) > 0 {
+		conn.endCursor = conn.edges[len(conn.edges)-1]
+	}
+
+	conn.hasPreviousPage = result.HasPreviousPage
+	conn.hasNextPage = result.HasNextPage
+
+	return conn
 }
 
 var connectionArgs = graphql.FieldConfigArgument{
@@ -33,6 +60,81 @@ var connectionArgs = graphql.FieldConfigArgument{
 	},
 }
 
+var recursiveConnectionArgs = graphql.FieldConfigArgument{
+	"before": connectionArgs["before"],
+	"after":  connectionArgs["after"],
+	"first":  connectionArgs["first"],
+	"last":   connectionArgs["last"],
+	"depth": &graphql.ArgumentConfig{
+		Type:        graphql.Int,
+		Description: "The maximum amount of levels to traverse.",
+	},
+}
+
+// pageSizes maps object names to the page sizes of their connections.
+var pageSizes = map[string]db.PageSize{}
+
+// PageSizes returns the page sizes of the connections of the objects of the last created schema by object names.
+func PageSizes() map[string]db.PageSize {
+	return pageSizes
+}
+
+func initPageSizes(g *graph.Graph, c *config.Config) error {
+	pageSizes = map[string]db.PageSize{}
+
+	var err error
+	g.Nodes().FilterObjects().ForEach(func(obj *graph.Node) bool {
+		objName := obj.GetAttrValueDefault("name", "")
+		referencedTable := g.Edges().FilterSource(obj).FilterEdgeType("objectHasTable").Targets().First()
+		if referencedTable == nil {
+			err = errors.New("referenced table not found")
+			return false
+		}
+
+		pagination := c.Pagination
+		tablePagination := c.Tables[referencedTable.GetAttrValueDefault("name", "")].Pagination
+		if tablePagination.DefaultPageSize != 0 {
+			pagination.DefaultPageSize = tablePagination.DefaultPageSize
+		}
+		if tablePagination.MaxPageSize != 0 {
+			pagination.MaxPageSize = tablePagination.MaxPageSize
+		}
+
+		if pagination.DefaultPageSize < 0 || pagination.MaxPageSize < 0 {
+			err = errors.Errorf("negative page size of object %s", objName)
+			return false
+		}
+		if pagination.MaxPageSize > 0 && pagination.DefaultPageSize > pagination.MaxPageSize {
+			err = errors.Errorf("default page size of object %s exceeds its maximum page size", objName)
+			return false
+		}
+
+		pageSizes[objName] = db.PageSize{
+			Default: uint(pagination.DefaultPageSize),
+			Max:     uint(pagination.MaxPageSize),
+		}
+
+		return true
+	})
+
+	return err
+}
+
+func getDepthArg(p graphql.ResolveParams) (*uint, error) {
+	depthValue, ok := p.Args["depth"]
+	if !ok {
+		return nil, nil
+	}
+
+	depth, ok := depthValue.(int)
+	if !ok || depth < 1 {
+		return nil, errors.Errorf("Invalid value depth '%v'", depthValue)
+	}
+	depthUint := uint(depth)
+
+	return &depthUint, nil
+}
+
 func getConnectionArgs(p graphql.ResolveParams, objName string) (*uint, *uint, *uint, *uint, error) {
 	var (
 		before *uint
@@ -64,7 +166,7 @@ func getConnectionArgs(p graphql.ResolveParams, objName string) (*uint, *uint, *
 	}
 
 	if firstValue, ok := p.Args["first"]; ok {
-		if firstValue, ok := firstValue.(int); ok {
+		if firstValue, ok := firstValue.(int); ok && firstValue >= 0 {
 			firstValueUint := uint(firstValue)
 			first = &firstValueUint
 		} else {
@@ -73,7 +175,7 @@ func getConnectionArgs(p graphql.ResolveParams, objName string) (*uint, *uint, *
 	}
 
 	if lastValue, ok := p.Args["last"]; ok {
-		if lastValue, ok := lastValue.(int); ok {
+		if lastValue, ok := lastValue.(int); ok && lastValue >= 0 {
 			lastValueUint := uint(lastValue)
 			last = &lastValueUint
 		} else {
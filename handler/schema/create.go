@@ -0,0 +1,444 @@
+package schema
+
+import (
+	"context"
+	"dynamic-graphql-api/handler/schema/db"
+	"dynamic-graphql-api/handler/schema/graph"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/iancoleman/strcase"
+	"github.com/jinzhu/inflection"
+	"github.com/pkg/errors"
+)
+
+// creator creates the rows of an object together with the rows of its nested inputs.
+type creator struct {
+	objName        string
+	table          string
+	mutationFields map[string]mutationField
+	// version is the version column of the table, nil if it has none.
+	version *db.Version
+	// createdAt and updatedAt are the columns of managed timestamps, empty if the table has none.
+	createdAt string
+	updatedAt string
+	// nested maps input field names to the nested creates of back-references and joined references.
+	nested map[string]nestedCreate
+}
+
+// nestedCreate is an input field creating the rows referencing the created row (e.g. lineItems) or associating
+// existing rows by their IDs (e.g. tagIds).
+type nestedCreate struct {
+	referenceType string
+	// objName is the object of the created or associated rows.
+	objName string
+
+	// column is the foreign key column of back-referencing rows.
+	column     string
+	isOneToOne bool
+
+	// joinTable, ownColumn and foreignColumn associate the rows of joined references.
+	joinTable     string
+	ownColumn     string
+	foreignColumn string
+}
+
+// creators maps object names to their creators.
+var creators = map[string]*creator{}
+
+// createInputs contains the create inputs by their names, including the inputs of nested creates (e.g.
+// CreatePostWithoutUserInput).
+var createInputs = map[string]*graphql.InputObject{}
+
+func initCreators(g *graph.Graph) error {
+	creators = map[string]*creator{}
+	createInputs = map[string]*graphql.InputObject{}
+
+	var err error
+	g.Nodes().FilterObjects().ForEach(func(obj *graph.Node) bool {
+		objName := obj.GetAttrValueDefault("name", "")
+
+		table := g.Edges().FilterSource(obj).FilterEdgeType("objectHasTable").Targets().First()
+		if table == nil {
+			err = errors.New("referenced table not found")
+			return false
+		}
+
+		mutationFields, errTemp := getMutationFields(g, g.Edges().FilterSource(obj).FilterEdgeType("objectHasField").Targets().All())
+		if errTemp != nil {
+			err = errTemp
+			return false
+		}
+
+		version, errTemp := applyVersion(table.GetAttrValueDefault("name", ""), mutationFields)
+		if errTemp != nil {
+			err = errTemp
+			return false
+		}
+
+		c := &creator{
+			objName:        objName,
+			table:          table.GetAttrValueDefault("name", ""),
+			mutationFields: mutationFields,
+			version:        version,
+			nested:         map[string]nestedCreate{},
+		}
+		for _, fieldDefinition := range mutationFields {
+			switch fieldDefinition.managedTimestamp {
+			case "created":
+				c.createdAt = fieldDefinition.column
+			case "updated":
+				c.updatedAt = fieldDefinition.column
+			}
+		}
+		creators[objName] = c
+
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	// nested creates reference the creators of other objects
+	g.Nodes().FilterObjects().ForEach(func(obj *graph.Node) bool {
+		c := creators[obj.GetAttrValueDefault("name", "")]
+
+		g.Edges().FilterSource(obj).FilterEdgeType("objectHasField").Targets().ForEach(func(field *graph.Node) bool {
+			referencedObject := g.Edges().FilterSource(field).FilterEdgeType("fieldReferencesObject").Targets().First()
+			if referencedObject == nil {
+				return true
+			}
+			fieldName := field.GetAttrValueDefault("name", "")
+			nested := nestedCreate{objName: referencedObject.GetAttrValueDefault("name", "")}
+			if _, ok := creators[nested.objName]; !ok {
+				return true
+			}
+
+			switch field.GetAttrValueDefault("referenceType", "") {
+			case "backward":
+				// back-referencing rows can only reference the primary key, which is known after the insert
+				ownColumn := g.Edges().FilterSource(field).FilterEdgeType("fieldHasColumn").Targets().First()
+				column := g.Edges().FilterSource(field).FilterEdgeType("fieldReferencesColumn").Targets().First()
+				if ownColumn == nil || column == nil || !ownColumn.HasAttrValue("isPrimaryKey", "true") {
+					return true
+				}
+
+				nested.referenceType = "backward"
+				nested.column = column.GetAttrValueDefault("name", "")
+				nested.isOneToOne = field.HasAttrValue("isOneToOne", "true")
+			case "joined":
+				joinTable := g.Edges().FilterSource(field).FilterEdgeType("fieldReferencesJoinTable").Targets().First()
+				ownColumn := g.Edges().FilterSource(field).FilterEdgeType("fieldReferencesOwnJoinColumn").Targets().First()
+				foreignColumn := g.Edges().FilterSource(field).FilterEdgeType("fieldReferencesForeignJoinColumn").Targets().First()
+				if joinTable == nil || ownColumn == nil || foreignColumn == nil {
+					err = errors.Errorf("incomplete joined reference %s.%s", c.objName, fieldName)
+					return false
+				}
+				for _, column := range []*graph.Node{ownColumn, foreignColumn} {
+					referencedColumn := g.Edges().FilterSource(column).FilterEdgeType("foreignKeyReferenceColumn").Targets().First()
+					if referencedColumn == nil || !referencedColumn.HasAttrValue("isPrimaryKey", "true") {
+						return true
+					}
+				}
+
+				nested.referenceType = "joined"
+				nested.joinTable = joinTable.GetAttrValueDefault("name", "")
+				nested.ownColumn = ownColumn.GetAttrValueDefault("name", "")
+				nested.foreignColumn = foreignColumn.GetAttrValueDefault("name", "")
+				fieldName = strcase.ToLowerCamel(inflection.Singular(fieldName) + "_ids")
+			default:
+				return true
+			}
+
+			if _, ok := c.mutationFields[fieldName]; ok {
+				// columns take precedence over nested creates
+				return true
+			}
+			c.nested[fieldName] = nested
+
+			return true
+		})
+
+		return err == nil
+	})
+
+	return err
+}
+
+// input returns the create input of the object. Nested inputs omit the foreign key column referencing the parent,
+// which is set automatically.
+func (c *creator) input(omittedColumn string) *graphql.InputObject {
+	name := "Create" + c.objName + "Input"
+	if omittedColumn != "" {
+		for fieldName, fieldDefinition := range c.mutationFields {
+			if fieldDefinition.column == omittedColumn {
+				name = "Create" + c.objName + "Without" + strings.TrimSuffix(strcase.ToCamel(fieldName), "Id") + "Input"
+			}
+		}
+	}
+	if input, ok := createInputs[name]; ok {
+		return input
+	}
+
+	createInputs[name] = graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: name,
+		Fields: graphql.InputObjectConfigFieldMapThunk(func() graphql.InputObjectConfigFieldMap {
+			fields := graphql.InputObjectConfigFieldMap{}
+			for fieldName, fieldDefinition := range c.mutationFields {
+				if fieldDefinition.fieldConfigCreate != nil && fieldDefinition.column != omittedColumn {
+					fields[fieldName] = fieldDefinition.fieldConfigCreate
+				}
+			}
+			if omittedColumn == "" {
+				fields["clientMutationId"] = &graphql.InputObjectFieldConfig{
+					Type: graphql.String,
+				}
+			}
+
+			for fieldName, nested := range c.nested {
+				switch nested.referenceType {
+				case "backward":
+					// one-to-one references are optional, only the items of lists are required
+					nestedInput := graphql.Input(creators[nested.objName].input(nested.column))
+					if !nested.isOneToOne {
+						nestedInput = graphql.NewList(graphql.NewNonNull(nestedInput))
+					}
+					fields[fieldName] = &graphql.InputObjectFieldConfig{
+						Type:        nestedInput,
+						Description: fmt.Sprintf("Creates the %s referencing the created object.", fieldName),
+					}
+				case "joined":
+					fields[fieldName] = &graphql.InputObjectFieldConfig{
+						Type:        graphql.NewList(graphql.NewNonNull(graphql.ID)),
+						Description: fmt.Sprintf("Associates the %s objects with the created object.", nested.objName),
+					}
+				}
+			}
+
+			return fields
+		}),
+	})
+
+	return createInputs[name]
+}
+
+// upsertInput returns the upsert input of the object. It omits nested inputs, which would be created again for
+// existing rows, and contains the version expected from existing rows.
+func (c *creator) upsertInput() *graphql.InputObject {
+	name := "Upsert" + c.objName + "Input"
+	if input, ok := createInputs[name]; ok {
+		return input
+	}
+
+	fields := graphql.InputObjectConfigFieldMap{}
+	for fieldName, fieldDefinition := range c.mutationFields {
+		if fieldDefinition.fieldConfigCreate != nil {
+			fields[fieldName] = fieldDefinition.fieldConfigCreate
+		}
+	}
+	if fieldName, ok := c.versionField(); ok {
+		fields[fieldName] = &graphql.InputObjectFieldConfig{
+			Type:        graphql.GetNullable(c.mutationFields[fieldName].fieldConfigUpdate.Type).(graphql.Input),
+			Description: "The version of the object read before, which is required if the object exists.",
+		}
+	}
+	fields["clientMutationId"] = &graphql.InputObjectFieldConfig{
+		Type: graphql.String,
+	}
+
+	createInputs[name] = graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:   name,
+		Fields: fields,
+	})
+
+	return createInputs[name]
+}
+
+// versionField returns the input field of the version column.
+func (c *creator) versionField() (string, bool) {
+	if c.version == nil {
+		return "", false
+	}
+
+	for fieldName, fieldDefinition := range c.mutationFields {
+		if fieldDefinition.column == c.version.Column {
+			return fieldName, true
+		}
+	}
+
+	return "", false
+}
+
+// columns converts a create input to the values of the new row. The values of fixed columns are set by the parent
+// of nested inputs.
+func (c *creator) columns(ctx context.Context, input map[string]interface{}, fixed map[string]interface{}) (map[string]interface{}, error) {
+	// check inputs availability (required & defined)
+	columns := map[string]interface{}{}
+	for name, value := range fixed {
+		columns[name] = value
+	}
+	if c.version != nil {
+		columns[c.version.Column] = c.version.Initial()
+	}
+	now := time.Now().UTC()
+	for _, column := range []string{c.createdAt, c.updatedAt} {
+		if column != "" {
+			columns[column] = now
+		}
+	}
+	for name, inputField := range input {
+		if _, ok := c.nested[name]; ok || name == "clientMutationId" {
+			continue
+		}
+
+		if fieldDefinition, ok := c.mutationFields[name]; ok && fieldDefinition.fieldConfigCreate != nil {
+			if err := checkColumn(ctx, c.table, fieldDefinition.column, "write"); err != nil {
+				return nil, err
+			}
+
+			valueType := fieldDefinition.fieldConfigCreate.Type.Name()
+			valueTypeWithoutNonNull := strings.TrimSuffix(valueType, "!")
+			if valueTypeWithoutNonNull == "ID" {
+				inputFieldString, ok := inputField.(string)
+				if !ok {
+					return nil, errors.Errorf("unknown id type of field %s", name)
+				}
+				cur, err := parseCursor(inputFieldString)
+				if err != nil {
+					return nil, err
+				}
+				if fieldDefinition.isPrimaryKey && cur.object != c.objName {
+					return nil, errors.Errorf("unexpected id type %s of field %s (expected %s)", cur.object, name, c.objName)
+				}
+				if !fieldDefinition.isPrimaryKey && cur.object != fieldDefinition.referencedObjectName {
+					return nil, errors.Errorf("unexpected id type %s of field %s (expected %s)", cur.object, name, fieldDefinition.referencedObjectName)
+				}
+
+				columns[fieldDefinition.column] = cur.id
+			} else {
+				columns[fieldDefinition.column] = inputField
+			}
+		} else {
+			return nil, errors.Errorf("unexpected input field %s", name)
+		}
+	}
+	for name, fieldDefinition := range c.mutationFields {
+		if fieldDefinition.fieldConfigCreate == nil {
+			continue
+		}
+		if _, ok := fixed[fieldDefinition.column]; ok {
+			continue
+		}
+
+		if _, ok := fieldDefinition.fieldConfigCreate.Type.(*graphql.NonNull); ok {
+			if _, ok := input[name]; !ok {
+				return nil, errors.Errorf("missing required input field %s", name)
+			}
+		}
+	}
+
+	return columns, nil
+}
+
+// insertColumns returns the columns, which are only set when creating rows (e.g. created_at).
+func (c *creator) insertColumns() []string {
+	if c.createdAt == "" {
+		return nil
+	}
+
+	return []string{c.createdAt}
+}
+
+// create creates a row of a nested input and the rows of its own nested inputs.
+func (c *creator) create(ctx context.Context, q db.Querier, input map[string]interface{}, fixed map[string]interface{}) (uint, error) {
+	if err := checkOperation(ctx, c.table, "create"); err != nil {
+		return 0, err
+	}
+
+	columns, err := c.columns(ctx, input, fixed)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := db.MutationCreateQuery(db.MutationCreateRequest{
+		Ctx: ctx,
+		DB:  q,
+
+		Table:        c.table,
+		ColumnValues: columns,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id, c.createNested(ctx, q, input, id)
+}
+
+// createNested creates the back-referencing rows and the associations of the nested inputs of the row with the id.
+func (c *creator) createNested(ctx context.Context, q db.Querier, input map[string]interface{}, id uint) error {
+	for name, nested := range c.nested {
+		value, ok := input[name]
+		if !ok || value == nil {
+			continue
+		}
+
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{value}
+		}
+
+		switch nested.referenceType {
+		case "backward":
+			for i, value := range values {
+				nestedInput, ok := value.(map[string]interface{})
+				if !ok {
+					return errors.Errorf("malformed input %s", name)
+				}
+
+				if _, err := creators[nested.objName].create(ctx, q, nestedInput, map[string]interface{}{nested.column: id}); err != nil {
+					if err, ok := inputError(err, name, strconv.Itoa(i)).(UserError); ok {
+						return err
+					}
+					return errors.Wrapf(err, "%s %d", name, i)
+				}
+			}
+		case "joined":
+			if err := checkOperation(ctx, nested.joinTable, "create"); err != nil {
+				return err
+			}
+
+			for _, value := range values {
+				cur, err := parseCursor(fmt.Sprintf("%v", value))
+				if err != nil {
+					return err
+				}
+				if cur.object != nested.objName {
+					return errors.Errorf("unexpected id type %s of field %s (expected %s)", cur.object, name, nested.objName)
+				}
+
+				err = db.MutationAssociateQuery(db.MutationAssociateRequest{
+					Ctx: ctx,
+					DB:  q,
+
+					Table: nested.joinTable,
+					ColumnValues: map[string]interface{}{
+						nested.ownColumn:     id,
+						nested.foreignColumn: cur.id,
+					},
+					// the created row itself may not be readable yet, only the referenced rows are restricted
+					Policies: map[string]*db.Policy{
+						nested.foreignColumn: getPolicy(ctx, nodeTables[nested.objName], "read"),
+					},
+				})
+				if err != nil {
+					return inputError(err, name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
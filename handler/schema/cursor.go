@@ -1,6 +1,11 @@
 package schema
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"dynamic-graphql-api/handler/config"
 	"encoding/base64"
 	"fmt"
 	"strconv"
@@ -12,6 +17,120 @@ import (
 type cursor struct {
 	object string
 	id     uint
+	// includeDeleted allows reading the fields of soft-deleted objects, which were read with includeDeleted.
+	includeDeleted bool
+}
+
+// cursorProtection protects cursors against forging, the first key is used for generating cursors, all keys are
+// accepted when parsing them. The ciphers of the aes mode are created once per key.
+var cursorProtection struct {
+	mode string
+	keys [][]byte
+	gcms []cipher.AEAD
+}
+
+func initCursor(c config.IDs) error {
+	cursorProtection.mode = c.Mode
+	cursorProtection.keys = nil
+	cursorProtection.gcms = nil
+
+	if c.Key == "" {
+		if c.Mode != "" || len(c.OldKeys) > 0 {
+			return errors.New("missing key for protecting ids")
+		}
+
+		return nil
+	}
+
+	switch c.Mode {
+	case "":
+		cursorProtection.mode = "hmac"
+	case "hmac", "aes":
+	default:
+		return errors.Errorf("unsupported id protection mode %s", c.Mode)
+	}
+
+	for _, key := range append([]string{c.Key}, c.OldKeys...) {
+		cursorProtection.keys = append(cursorProtection.keys, []byte(key))
+
+		if cursorProtection.mode == "aes" {
+			gcm, err := newGCM([]byte(key))
+			if err != nil {
+				return errors.Wrap(err, "failed to create cipher for protecting ids")
+			}
+			cursorProtection.gcms = append(cursorProtection.gcms, gcm)
+		}
+	}
+
+	return nil
+}
+
+func sign(key []byte, plain []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(plain)
+	return mac.Sum(nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	// derive a key with a valid AES key size from arbitrary keys
+	derivedKey := sha256.Sum256(key)
+	block, err := aes.NewCipher(derivedKey[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// protect signs or encrypts the plain cursor. Encryption uses a synthetic nonce derived from the plain cursor so that
+// the same object always results in the same ID.
+func protect(plain []byte) []byte {
+	if len(cursorProtection.keys) == 0 {
+		return plain
+	}
+
+	key := cursorProtection.keys[0]
+	if cursorProtection.mode == "aes" {
+		gcm := cursorProtection.gcms[0]
+		nonce := sign(key, plain)[:gcm.NonceSize()]
+		return gcm.Seal(nonce, nonce, plain, nil)
+	}
+
+	return append(append(plain, ':'), sign(key, plain)...)
+}
+
+// unprotect verifies or decrypts the protected cursor with any of the keys.
+func unprotect(protected []byte) ([]byte, bool) {
+	if len(cursorProtection.keys) == 0 {
+		return protected, true
+	}
+
+	for i, key := range cursorProtection.keys {
+		if cursorProtection.mode == "aes" {
+			gcm := cursorProtection.gcms[i]
+			if len(protected) < gcm.NonceSize() {
+				continue
+			}
+
+			plain, err := gcm.Open(nil, protected[:gcm.NonceSize()], protected[gcm.NonceSize():], nil)
+			if err == nil {
+				return plain, true
+			}
+
+			continue
+		}
+
+		// the signature is appended to the plain cursor separated by a colon
+		separator := len(protected) - sha256.Size - 1
+		if separator < 0 || protected[separator] != ':' {
+			continue
+		}
+		if hmac.Equal(protected[separator+1:], sign(key, protected[:separator])) {
+			return protected[:separator], true
+		}
+	}
+
+	return nil, false
 }
 
 func parseCursor(c string) (*cursor, error) {
@@ -20,6 +139,11 @@ func parseCursor(c string) (*cursor, error) {
 		return nil, errors.Errorf("invalid cursor '%s'", c)
 	}
 
+	bytesCursor, ok := unprotect(bytesCursor)
+	if !ok {
+		return nil, errors.Errorf("invalid cursor '%s'", c)
+	}
+
 	stringsID := strings.SplitN(string(bytesCursor), ":", 2)
 	if len(stringsID) != 2 {
 		return nil, errors.Errorf("invalid cursor '%s'", c)
@@ -38,5 +162,5 @@ func (c cursor) String() string {
 }
 
 func (c cursor) OpaqueString() string {
-	return base64.StdEncoding.EncodeToString([]byte(c.String()))
+	return base64.StdEncoding.EncodeToString(protect([]byte(c.String())))
 }
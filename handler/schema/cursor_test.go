@@ -0,0 +1,105 @@
+package schema
+
+import (
+	"dynamic-graphql-api/handler/config"
+	"encoding/base64"
+	"testing"
+)
+
+func TestInitCursorRejectsInvalidConfigurations(t *testing.T) {
+	defer initCursor(config.IDs{})
+
+	for _, ids := range []config.IDs{
+		{Mode: "aes"},
+		{OldKeys: []string{"old"}},
+		{Mode: "rot13", Key: "key"},
+	} {
+		if err := initCursor(ids); err == nil {
+			t.Errorf("expected an error for %+v", ids)
+		}
+	}
+}
+
+func TestCursorProtection(t *testing.T) {
+	defer initCursor(config.IDs{})
+
+	// flip flips a bit in the middle of the decoded ID
+	flip := func(id string) string {
+		b, err := base64.StdEncoding.DecodeString(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b[len(b)/2] ^= 1
+		return base64.StdEncoding.EncodeToString(b)
+	}
+
+	hmacKey := config.IDs{Key: "key"}
+	aesKey := config.IDs{Mode: "aes", Key: "key"}
+
+	tests := []struct {
+		name     string
+		generate config.IDs
+		parse    config.IDs
+		modify   func(string) string
+		valid    bool
+	}{
+		{"unprotected", config.IDs{}, config.IDs{}, nil, true},
+		{"hmac", hmacKey, hmacKey, nil, true},
+		{"aes", aesKey, aesKey, nil, true},
+		{"hmac old key", config.IDs{Key: "old"}, config.IDs{Key: "new", OldKeys: []string{"old"}}, nil, true},
+		{"aes old key", config.IDs{Mode: "aes", Key: "old"}, config.IDs{Mode: "aes", Key: "new", OldKeys: []string{"old"}}, nil, true},
+		{"hmac removed key", config.IDs{Key: "old"}, config.IDs{Key: "new"}, nil, false},
+		{"aes removed key", config.IDs{Mode: "aes", Key: "old"}, config.IDs{Mode: "aes", Key: "new"}, nil, false},
+		{"hmac tampered", hmacKey, hmacKey, flip, false},
+		{"aes tampered", aesKey, aesKey, flip, false},
+		{"aes truncated", aesKey, aesKey, func(id string) string { return id[:8] }, false},
+		{"unprotected id with hmac", config.IDs{}, hmacKey, nil, false},
+		{"unprotected id with aes", config.IDs{}, aesKey, nil, false},
+		{"hmac id with aes", hmacKey, aesKey, nil, false},
+		{"not base64", hmacKey, hmacKey, func(id string) string { return "!" + id }, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := initCursor(test.generate); err != nil {
+				t.Fatal(err)
+			}
+			id := cursor{object: "User", id: 42}.OpaqueString()
+			if test.modify != nil {
+				id = test.modify(id)
+			}
+
+			if err := initCursor(test.parse); err != nil {
+				t.Fatal(err)
+			}
+			c, err := parseCursor(id)
+			switch {
+			case test.valid && err != nil:
+				t.Fatalf("unexpected error %v", err)
+			case test.valid && (c.object != "User" || c.id != 42):
+				t.Fatalf("parsed %s:%d", c.object, c.id)
+			case !test.valid && err == nil:
+				t.Fatalf("accepted %s as %s:%d", id, c.object, c.id)
+			}
+		})
+	}
+}
+
+// IDs are compared by clients, so the same object must always result in the same ID.
+func TestCursorProtectionIsDeterministic(t *testing.T) {
+	defer initCursor(config.IDs{})
+
+	for _, mode := range []string{"hmac", "aes"} {
+		if err := initCursor(config.IDs{Mode: mode, Key: "key"}); err != nil {
+			t.Fatal(err)
+		}
+
+		first := cursor{object: "User", id: 1}.OpaqueString()
+		if first != (cursor{object: "User", id: 1}).OpaqueString() {
+			t.Errorf("%s: the same object resulted in different IDs", mode)
+		}
+		if first == (cursor{object: "User", id: 2}).OpaqueString() {
+			t.Errorf("%s: different objects resulted in the same ID", mode)
+		}
+	}
+}
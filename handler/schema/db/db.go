@@ -1,38 +1,139 @@
 package db
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+)
 
-// NewDB creates a new database connection and also returns all CREATE TABLE statements.
-func NewDB(driverName string, dataSourceName string) (*sql.DB, []string, error) {
+// Querier executes statements either directly on the database or inside a transaction.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// UniqueIndex represents a unique index which was created with CREATE UNIQUE INDEX.
+type UniqueIndex struct {
+	Table   string
+	Columns []string
+}
+
+// NewDB creates a new database connection and also returns all CREATE TABLE statements and unique indexes.
+func NewDB(driverName string, dataSourceName string) (*sql.DB, []string, []UniqueIndex, error) {
 	db, err := sql.Open(driverName, dataSourceName)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	rows, err := db.Query(
-		"SELECT sql FROM sqlite_master WHERE type = 'table'",
+		"SELECT name, sql FROM sqlite_master WHERE type = 'table'",
 	)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	defer rows.Close()
 
 	var (
-		sqlString string
-		sqls      []string
+		tableName  string
+		sqlString  string
+		tableNames []string
+		sqls       []string
 	)
 	for rows.Next() {
-		err := rows.Scan(&sqlString)
+		err := rows.Scan(&tableName, &sqlString)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 
+		tableNames = append(tableNames, tableName)
 		sqls = append(sqls, sqlString)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+
+	var uniqueIndexes []UniqueIndex
+	for _, tableName := range tableNames {
+		tableUniqueIndexes, err := queryUniqueIndexes(db, tableName)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		uniqueIndexes = append(uniqueIndexes, tableUniqueIndexes...)
+	}
+
+	return db, sqls, uniqueIndexes, nil
+}
+
+// queryUniqueIndexes returns the unique indexes of a table. Indexes originating from constraints in the CREATE
+// TABLE statement, partial indexes and indexes on expressions are skipped.
+func queryUniqueIndexes(db *sql.DB, table string) ([]UniqueIndex, error) {
+	rows, err := db.Query(
+		"SELECT name FROM pragma_index_list(?) WHERE \"unique\" = 1 AND origin = 'c' AND partial = 0",
+		table,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		indexName  string
+		indexNames []string
+	)
+	for rows.Next() {
+		if err := rows.Scan(&indexName); err != nil {
+			return nil, err
+		}
+
+		indexNames = append(indexNames, indexName)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var uniqueIndexes []UniqueIndex
+	for _, indexName := range indexNames {
+		uniqueIndex, err := queryUniqueIndex(db, table, indexName)
+		if err != nil {
+			return nil, err
+		}
+
+		if uniqueIndex != nil {
+			uniqueIndexes = append(uniqueIndexes, *uniqueIndex)
+		}
+	}
+
+	return uniqueIndexes, nil
+}
+
+func queryUniqueIndex(db *sql.DB, table string, index string) (*UniqueIndex, error) {
+	rows, err := db.Query("SELECT name FROM pragma_index_info(?) ORDER BY seqno", index)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	uniqueIndex := &UniqueIndex{Table: table}
+	var columnName sql.NullString
+	for rows.Next() {
+		if err := rows.Scan(&columnName); err != nil {
+			return nil, err
+		}
+
+		if !columnName.Valid {
+			// index on expression
+			return nil, nil
+		}
+
+		uniqueIndex.Columns = append(uniqueIndex.Columns, columnName.String)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return db, sqls, nil
+	return uniqueIndex, nil
 }
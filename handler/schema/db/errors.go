@@ -0,0 +1,71 @@
+package db
+
+import (
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ConstraintError is returned if a statement violates a constraint of a table.
+type ConstraintError struct {
+	// Code is one of UNIQUE_VIOLATION, FOREIGN_KEY_VIOLATION, NOT_NULL_VIOLATION and CHECK_VIOLATION.
+	Code string
+	// Table and Columns are the violating columns, which are unknown for foreign key constraints. Check constraints
+	// only contain the name of the constraint in Constraint.
+	Table      string
+	Columns    []string
+	Constraint string
+
+	err error
+}
+
+func (e ConstraintError) Error() string {
+	return e.err.Error()
+}
+
+// constraintCodes maps the extended result codes of SQLite to the codes of constraint errors.
+var constraintCodes = map[sqlite3.ErrNoExtended]string{
+	sqlite3.ErrConstraintUnique:     "UNIQUE_VIOLATION",
+	sqlite3.ErrConstraintPrimaryKey: "UNIQUE_VIOLATION",
+	sqlite3.ErrConstraintForeignKey: "FOREIGN_KEY_VIOLATION",
+	sqlite3.ErrConstraintNotNull:    "NOT_NULL_VIOLATION",
+	sqlite3.ErrConstraintCheck:      "CHECK_VIOLATION",
+}
+
+// constraintError translates constraint violations of SQLite (e.g. UNIQUE constraint failed: users.email) to
+// constraint errors, other errors are returned as they are.
+func constraintError(err error) error {
+	sqliteErr, ok := err.(sqlite3.Error)
+	if !ok {
+		return err
+	}
+	code, ok := constraintCodes[sqliteErr.ExtendedCode]
+	if !ok {
+		return err
+	}
+
+	e := ConstraintError{Code: code, err: err}
+
+	// the details follow the colon, e.g. users.email, users.name or the name of a check constraint
+	message := sqliteErr.Error()
+	i := strings.Index(message, ": ")
+	if i < 0 {
+		return e
+	}
+	details := message[i+2:]
+
+	if code == "CHECK_VIOLATION" {
+		e.Constraint = details
+		return e
+	}
+	for _, tableColumn := range strings.Split(details, ", ") {
+		parts := strings.SplitN(tableColumn, ".", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		e.Table = parts[0]
+		e.Columns = append(e.Columns, parts[1])
+	}
+
+	return e
+}
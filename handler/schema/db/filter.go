@@ -0,0 +1,85 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Filter is a condition on the rows of a table. It either compares a column by its operator or combines the filters
+// of And or Or.
+type Filter struct {
+	Column string
+	// Operator is one of =, <>, <, <=, >, >=, IN, NOT IN, IS NULL or IS NOT NULL.
+	Operator string
+	// Value is the compared value, a slice for IN and NOT IN and ignored for null checks.
+	Value interface{}
+
+	And []Filter
+	Or  []Filter
+}
+
+// where returns the filter as parameterized SQL expression.
+func (f Filter) where() (string, []interface{}) {
+	if f.Column != "" {
+		switch f.Operator {
+		case "IS NULL", "IS NOT NULL":
+			return fmt.Sprintf("%s %s", f.Column, f.Operator), nil
+		case "IN", "NOT IN":
+			values, _ := f.Value.([]interface{})
+			return fmt.Sprintf("%s %s (%s)", f.Column, f.Operator, placeholders(len(values))), values
+		default:
+			return fmt.Sprintf("%s %s ?", f.Column, f.Operator), []interface{}{f.Value}
+		}
+	}
+
+	if f.Or != nil {
+		return combineFilters(f.Or, "OR", "0")
+	}
+
+	return combineFilters(f.And, "AND", "1")
+}
+
+// MatchesAll returns whether the filter has no effective conditions (e.g. an empty filter, an AND of empty filters
+// or an OR with an empty filter).
+func (f Filter) MatchesAll() bool {
+	if f.Column != "" {
+		return false
+	}
+
+	if f.Or != nil {
+		for _, filter := range f.Or {
+			if filter.MatchesAll() {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for _, filter := range f.And {
+		if !filter.MatchesAll() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// combineFilters joins the expressions of filters by the operator, empty is the expression without filters.
+func combineFilters(filters []Filter, operator string, empty string) (string, []interface{}) {
+	if len(filters) == 0 {
+		return empty, nil
+	}
+
+	var (
+		exprs []string
+		args  []interface{}
+	)
+	for _, filter := range filters {
+		expr, filterArgs := filter.where()
+		exprs = append(exprs, expr)
+		args = append(args, filterArgs...)
+	}
+
+	return "(" + strings.Join(exprs, " "+operator+" ") + ")", args
+}
@@ -0,0 +1,184 @@
+package db
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func TestFilterWhere(t *testing.T) {
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	_, err = database.Exec(`CREATE TABLE tasks (id INTEGER PRIMARY KEY, title TEXT, priority INTEGER);
+		INSERT INTO tasks (title, priority) VALUES ('a', 1), ('b', 2), ('c', NULL)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		filter     Filter
+		expression string
+		args       []interface{}
+		ids        []int
+		matchesAll bool
+	}{
+		{
+			name:       "empty",
+			filter:     Filter{},
+			expression: "1",
+			ids:        []int{1, 2, 3},
+			matchesAll: true,
+		},
+		{
+			name:       "comparison",
+			filter:     Filter{Column: "priority", Operator: ">=", Value: 2},
+			expression: "priority >= ?",
+			args:       []interface{}{2},
+			ids:        []int{2},
+		},
+		{
+			name:       "in",
+			filter:     Filter{Column: "title", Operator: "IN", Value: []interface{}{"a", "c"}},
+			expression: "title IN (?, ?)",
+			args:       []interface{}{"a", "c"},
+			ids:        []int{1, 3},
+		},
+		{
+			name:       "empty in",
+			filter:     Filter{Column: "title", Operator: "IN", Value: []interface{}{}},
+			expression: "title IN ()",
+			args:       []interface{}{},
+			ids:        nil,
+		},
+		{
+			name:       "not in",
+			filter:     Filter{Column: "title", Operator: "NOT IN", Value: []interface{}{"a"}},
+			expression: "title NOT IN (?)",
+			args:       []interface{}{"a"},
+			ids:        []int{2, 3},
+		},
+		{
+			name:       "null check",
+			filter:     Filter{Column: "priority", Operator: "IS NULL", Value: true},
+			expression: "priority IS NULL",
+			ids:        []int{3},
+		},
+		{
+			name: "and",
+			filter: Filter{And: []Filter{
+				{Column: "priority", Operator: "IS NOT NULL"},
+				{Column: "title", Operator: "<>", Value: "a"},
+			}},
+			expression: "(priority IS NOT NULL AND title <> ?)",
+			args:       []interface{}{"a"},
+			ids:        []int{2},
+		},
+		{
+			name: "or",
+			filter: Filter{Or: []Filter{
+				{Column: "priority", Operator: "=", Value: 1},
+				{Column: "priority", Operator: "IS NULL"},
+			}},
+			expression: "(priority = ? OR priority IS NULL)",
+			args:       []interface{}{1},
+			ids:        []int{1, 3},
+		},
+		{
+			name:       "empty or",
+			filter:     Filter{Or: []Filter{}},
+			expression: "0",
+			ids:        nil,
+		},
+		{
+			name: "nested",
+			filter: Filter{And: []Filter{
+				{Or: []Filter{
+					{Column: "title", Operator: "=", Value: "a"},
+					{Column: "title", Operator: "=", Value: "b"},
+				}},
+				{Column: "priority", Operator: "<", Value: 2},
+			}},
+			expression: "((title = ? OR title = ?) AND priority < ?)",
+			args:       []interface{}{"a", "b", 2},
+			ids:        []int{1},
+		},
+		{
+			name:       "or of an empty filter",
+			filter:     Filter{Or: []Filter{{}}},
+			expression: "(1)",
+			ids:        []int{1, 2, 3},
+			matchesAll: true,
+		},
+		{
+			name: "or of an empty filter and a comparison",
+			filter: Filter{Or: []Filter{
+				{},
+				{Column: "title", Operator: "=", Value: "x"},
+			}},
+			expression: "(1 OR title = ?)",
+			args:       []interface{}{"x"},
+			ids:        []int{1, 2, 3},
+			matchesAll: true,
+		},
+		{
+			name: "and of an or of an empty filter",
+			filter: Filter{And: []Filter{
+				{Or: []Filter{{}, {Column: "title", Operator: "=", Value: "x"}}},
+				{},
+			}},
+			expression: "((1 OR title = ?) AND 1)",
+			args:       []interface{}{"x"},
+			ids:        []int{1, 2, 3},
+			matchesAll: true,
+		},
+		{
+			name:       "and of empty filters",
+			filter:     Filter{And: []Filter{{}, {And: []Filter{}}}},
+			expression: "(1 AND 1)",
+			ids:        []int{1, 2, 3},
+			matchesAll: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expression, args := test.filter.where()
+			if expression != test.expression {
+				t.Fatalf("expected %q, got %q", test.expression, expression)
+			}
+			if !reflect.DeepEqual(args, test.args) {
+				t.Fatalf("expected args %v, got %v", test.args, args)
+			}
+			if test.filter.MatchesAll() != test.matchesAll {
+				t.Fatalf("expected matches all %t", test.matchesAll)
+			}
+
+			rows, err := database.Query("SELECT id FROM tasks WHERE "+expression+" ORDER BY id", args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+
+			var ids []int
+			for rows.Next() {
+				var id int
+				if err := rows.Scan(&id); err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, id)
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(ids, test.ids) {
+				t.Fatalf("expected ids %v, got %v", test.ids, ids)
+			}
+		})
+	}
+}
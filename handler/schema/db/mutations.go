@@ -4,13 +4,16 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
 	"strings"
+
+	"github.com/pkg/errors"
 )
 
 // MutationCreateRequest describes the query.
 type MutationCreateRequest struct {
 	Ctx context.Context
-	DB  *sql.DB
+	DB  Querier
 
 	Table        string
 	ColumnValues map[string]interface{}
@@ -31,10 +34,128 @@ func MutationCreateQuery(r MutationCreateRequest) (uint, error) {
 		r.Ctx,
 		fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", r.Table, strings.Join(columnNames, ", "), strings.Join(columnValueStrings, ", ")),
 		columnValues...)
+	if err != nil {
+		return 0, constraintError(err)
+	}
+
+	insertedID, err := result.LastInsertId()
 	if err != nil {
 		return 0, err
 	}
 
+	return uint(insertedID), nil
+}
+
+// MutationUpsertRequest describes the query.
+type MutationUpsertRequest struct {
+	Ctx context.Context
+	DB  Querier
+
+	Table           string
+	ColumnValues    map[string]interface{}
+	ConflictColumns []string
+	// InsertColumns are only set when inserting rows (e.g. created_at), existing rows keep their values.
+	InsertColumns []string
+
+	// Version is compared to ExpectedVersion and changed when updating existing rows, nil if the table has no
+	// version column.
+	Version         *Version
+	ExpectedVersion interface{}
+
+	// Policy restricts updating existing rows to accessible rows.
+	Policy *Policy
+}
+
+// MutationUpsertQuery creates a row or updates the row with the same values in the conflict columns, which form a
+// unique key, and returns the id of the row. Existing rows are only updated if their version matches.
+func MutationUpsertQuery(r MutationUpsertRequest) (uint, error) {
+	var id uint
+	err := InTransaction(r.Ctx, r.DB, func(q Querier) error {
+		var err error
+		id, err = upsertRow(r, q)
+		return err
+	})
+
+	return id, err
+}
+
+func upsertRow(r MutationUpsertRequest, q Querier) (uint, error) {
+	conflictColumns := map[string]bool{}
+	insertColumns := map[string]bool{}
+	for _, name := range r.InsertColumns {
+		insertColumns[name] = true
+	}
+	var keyExprs []string
+	var keyValues []interface{}
+	for _, name := range r.ConflictColumns {
+		value, ok := r.ColumnValues[name]
+		if !ok || value == nil {
+			return 0, errors.Errorf("missing value of conflict column %s", name)
+		}
+
+		conflictColumns[name] = true
+		keyExprs = append(keyExprs, fmt.Sprintf("%s = ?", name))
+		keyValues = append(keyValues, value)
+	}
+
+	var columnNames []string
+	var columnValues []interface{}
+	var updateExprs []string
+	var updateArgs []interface{}
+	for name, value := range r.ColumnValues {
+		columnNames = append(columnNames, name)
+		columnValues = append(columnValues, value)
+		if !conflictColumns[name] && !insertColumns[name] && (r.Version == nil || name != r.Version.Column) {
+			updateExprs = append(updateExprs, fmt.Sprintf("%s = excluded.%s", name, name))
+		}
+	}
+	if r.Version != nil {
+		setExpr, setArgs := r.Version.set()
+		updateExprs = append(updateExprs, setExpr)
+		updateArgs = append(updateArgs, setArgs...)
+	}
+	if len(updateExprs) == 0 {
+		// existing rows are left unchanged, but still count as affected
+		updateExprs = append(updateExprs, fmt.Sprintf("%s = excluded.%s", r.ConflictColumns[0], r.ConflictColumns[0]))
+	}
+
+	restriction, restrictionArgs := r.Policy.restrict(r.Table + ".id")
+
+	// rows without version match all versions, a missing expected version matches none
+	versionExpr, versionArgs := "1", []interface{}(nil)
+	if r.Version != nil {
+		versionExpr, versionArgs = r.Version.matches(r.ExpectedVersion)
+	}
+
+	// the id of an existing row tells whether the row is inserted or updated
+	var existingID uint
+	err := q.QueryRowContext(
+		r.Ctx,
+		fmt.Sprintf("SELECT id FROM %s WHERE %s", r.Table, strings.Join(keyExprs, " AND ")),
+		keyValues...).Scan(&existingID)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	inserted := err == sql.ErrNoRows
+
+	result, err := q.ExecContext(
+		r.Ctx,
+		fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s WHERE %s AND %s",
+			r.Table, strings.Join(columnNames, ", "), placeholders(len(columnNames)), strings.Join(r.ConflictColumns, ", "), strings.Join(updateExprs, ", "), restriction, versionExpr),
+		append(append(append(columnValues, updateArgs...), restrictionArgs...), versionArgs...)...)
+	if err != nil {
+		return 0, constraintError(err)
+	}
+	if err := checkRowsAffected(result); err != nil && r.Version != nil && !inserted {
+		return 0, checkConflict(r.Ctx, q, r.Table, "id", []interface{}{existingID}, r.Version, r.Policy)
+	} else if err != nil {
+		return 0, err
+	}
+
+	if !inserted {
+		return existingID, nil
+	}
+
 	insertedID, err := result.LastInsertId()
 	if err != nil {
 		return 0, err
@@ -46,11 +167,17 @@ func MutationCreateQuery(r MutationCreateRequest) (uint, error) {
 // MutationUpdateRequest describes the query.
 type MutationUpdateRequest struct {
 	Ctx context.Context
-	DB  *sql.DB
+	DB  Querier
 
 	Table                string
 	ColumnValues         map[string]interface{}
 	ColumnWithPrimaryKey string
+
+	// Version is compared to its value in ColumnValues and changed, nil if the table has no version column.
+	Version *Version
+
+	// Policy restricts the update to accessible rows.
+	Policy *Policy
 }
 
 // MutationUpdateQuery updates a row in the database.
@@ -61,21 +188,39 @@ func MutationUpdateQuery(r MutationUpdateRequest) error {
 	var columnID string
 	var columnIDValue interface{}
 
+	// rows without version match all versions
+	versionExpr, versionArgs := "1", []interface{}(nil)
+
 	for name, value := range r.ColumnValues {
 		if name == r.ColumnWithPrimaryKey {
 			columnID = name
 			columnIDValue = value
+		} else if r.Version != nil && name == r.Version.Column {
+			versionExpr, versionArgs = r.Version.matches(value)
 		} else {
 			columnExprs = append(columnExprs, fmt.Sprintf("%s = ?", name))
 			columnValues = append(columnValues, value)
 		}
 	}
+	if r.Version != nil {
+		setExpr, setArgs := r.Version.set()
+		columnExprs = append(columnExprs, setExpr)
+		columnValues = append(columnValues, setArgs...)
+	}
 
-	_, err := r.DB.ExecContext(
+	restriction, restrictionArgs := r.Policy.restrict(columnID)
+
+	result, err := r.DB.ExecContext(
 		r.Ctx,
-		fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?", r.Table, strings.Join(columnExprs, ", "), columnID),
-		append(columnValues, columnIDValue)...)
+		fmt.Sprintf("UPDATE %s SET %s WHERE %s = ? AND %s AND %s", r.Table, strings.Join(columnExprs, ", "), columnID, versionExpr, restriction),
+		append(append(append(columnValues, columnIDValue), versionArgs...), restrictionArgs...)...)
 	if err != nil {
+		return constraintError(err)
+	}
+
+	if err := checkRowsAffected(result); err != nil && r.Version != nil {
+		return checkConflict(r.Ctx, r.DB, r.Table, columnID, []interface{}{columnIDValue}, r.Version, r.Policy)
+	} else if err != nil {
 		return err
 	}
 
@@ -85,22 +230,46 @@ func MutationUpdateQuery(r MutationUpdateRequest) error {
 // MutationDeleteRequest describes the query.
 type MutationDeleteRequest struct {
 	Ctx context.Context
-	DB  *sql.DB
+	DB  Querier
 
 	Table       string
 	ColumnName  string
 	ColumnValue interface{}
+
+	// DeletedAt is the column of soft deletes, which is set to the current time instead of deleting the row.
+	DeletedAt string
+
+	// Policy restricts the deletion to accessible rows.
+	Policy *Policy
 }
 
 // MutationDeleteQuery deletes a row from the database.
 func MutationDeleteQuery(r MutationDeleteRequest) error {
-	_, err := r.DB.ExecContext(
-		r.Ctx,
-		fmt.Sprintf("DELETE FROM %s WHERE %s = ?", r.Table, r.ColumnName),
-		r.ColumnValue)
+	restriction, restrictionArgs := r.Policy.restrict(r.ColumnName)
+
+	statement, args := deleteStatement(
+		r.Table,
+		r.DeletedAt,
+		fmt.Sprintf("%s = ? AND %s", r.ColumnName, restriction),
+		append([]interface{}{r.ColumnValue}, restrictionArgs...))
+	result, err := r.DB.ExecContext(r.Ctx, statement, args...)
+	if err != nil {
+		return constraintError(err)
+	}
+
+	return checkRowsAffected(result)
+}
+
+// checkRowsAffected returns an error if a mutation did not affect any row because the row does not exist or is not
+// accessible.
+func checkRowsAffected(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
+	if rowsAffected == 0 {
+		return errors.New("row not found")
+	}
 
 	return nil
 }
@@ -108,10 +277,13 @@ func MutationDeleteQuery(r MutationDeleteRequest) error {
 // MutationAssociateRequest describes the query.
 type MutationAssociateRequest struct {
 	Ctx context.Context
-	DB  *sql.DB
+	DB  Querier
 
 	Table        string
 	ColumnValues map[string]interface{}
+
+	// Policies restrict the associated rows referenced by the columns to accessible rows.
+	Policies map[string]*Policy
 }
 
 // MutationAssociateQuery associates two rows in the database.
@@ -119,30 +291,43 @@ func MutationAssociateQuery(r MutationAssociateRequest) error {
 	var columnNames []string
 	var columnValueStrings []string
 	var columnValues []interface{}
+	var restrictions []string
+	var restrictionArgs []interface{}
 	for name, value := range r.ColumnValues {
 		columnNames = append(columnNames, name)
 		columnValueStrings = append(columnValueStrings, "?")
 		columnValues = append(columnValues, value)
+
+		// the referenced row is compared to the inserted value
+		if policy, ok := r.Policies[name]; ok && policy != nil {
+			restriction, args := policy.restrict("?")
+			restrictions = append(restrictions, restriction)
+			restrictionArgs = append(append(restrictionArgs, value), args...)
+		}
 	}
+	restrictions = append(restrictions, "1")
 
-	_, err := r.DB.ExecContext(
+	result, err := r.DB.ExecContext(
 		r.Ctx,
-		fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", r.Table, strings.Join(columnNames, ", "), strings.Join(columnValueStrings, ", ")),
-		columnValues...)
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s WHERE %s", r.Table, strings.Join(columnNames, ", "), strings.Join(columnValueStrings, ", "), strings.Join(restrictions, " AND ")),
+		append(columnValues, restrictionArgs...)...)
 	if err != nil {
-		return err
+		return constraintError(err)
 	}
 
-	return nil
+	return checkRowsAffected(result)
 }
 
 // MutationDisassociateRequest describes the query.
 type MutationDisassociateRequest struct {
 	Ctx context.Context
-	DB  *sql.DB
+	DB  Querier
 
 	Table        string
 	ColumnValues map[string]interface{}
+
+	// Policies restrict the disassociated rows referenced by the columns to accessible rows.
+	Policies map[string]*Policy
 }
 
 // MutationDisassociateQuery disassociates two rows in the database.
@@ -154,14 +339,440 @@ func MutationDisassociateQuery(r MutationDisassociateRequest) error {
 		columnExprs = append(columnExprs, fmt.Sprintf("%s = ?", name))
 		columnValues = append(columnValues, value)
 	}
+	for name, policy := range r.Policies {
+		if policy == nil {
+			continue
+		}
+
+		restriction, args := policy.restrict(name)
+		columnExprs = append(columnExprs, restriction)
+		columnValues = append(columnValues, args...)
+	}
 
 	_, err := r.DB.ExecContext(
 		r.Ctx,
 		fmt.Sprintf("DELETE FROM %s WHERE %s", r.Table, strings.Join(columnExprs, " AND ")),
 		columnValues...)
 	if err != nil {
+		return constraintError(err)
+	}
+
+	return nil
+}
+
+// maxVariables is the maximum amount of parameters of a statement supported by SQLite.
+const maxVariables = 999
+
+// InTransaction executes fn inside a transaction, which is started if q is not a transaction yet.
+func InTransaction(ctx context.Context, q Querier, fn func(q Querier) error) error {
+	db, ok := q.(*sql.DB)
+	if !ok {
+		return fn(q)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
 		return err
 	}
 
+	return tx.Commit()
+}
+
+// MutationCreateManyRequest describes the query.
+type MutationCreateManyRequest struct {
+	Ctx context.Context
+	DB  Querier
+
+	Table string
+	Rows  []map[string]interface{}
+}
+
+// MutationCreateManyQuery creates rows in the database and returns the created ids in the order of the rows. The rows
+// are inserted one by one, since the ids of a multi-row statement are only known if they are consecutive, which rows
+// with explicit ids break.
+func MutationCreateManyQuery(r MutationCreateManyRequest) ([]uint, error) {
+	var insertedIDs []uint
+	err := InTransaction(r.Ctx, r.DB, func(q Querier) error {
+		for _, row := range r.Rows {
+			id, err := insertRow(r.Ctx, q, r.Table, row)
+			if err != nil {
+				return err
+			}
+			insertedIDs = append(insertedIDs, id)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return insertedIDs, nil
+}
+
+// insertRow inserts a row, rows without columns consist of the default values.
+func insertRow(ctx context.Context, q Querier, table string, row map[string]interface{}) (uint, error) {
+	if len(row) == 0 {
+		result, err := q.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", table))
+		if err != nil {
+			return 0, constraintError(err)
+		}
+		insertedID, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+
+		return uint(insertedID), nil
+	}
+
+	return MutationCreateQuery(MutationCreateRequest{
+		Ctx: ctx,
+		DB:  q,
+
+		Table:        table,
+		ColumnValues: row,
+	})
+}
+
+// MutationUpdateManyRequest describes the query.
+type MutationUpdateManyRequest struct {
+	Ctx context.Context
+	DB  Querier
+
+	Table                string
+	Rows                 []map[string]interface{}
+	ColumnWithPrimaryKey string
+
+	// Version is compared to its value in each row and changed, nil if the table has no version column.
+	Version *Version
+
+	// Policy restricts the update to accessible rows.
+	Policy *Policy
+}
+
+// MutationUpdateManyQuery updates rows in the database, each row identified by the value of the primary key column.
+// The rows are updated by one statement assigning the values per id, as far as the parameter limit allows.
+func MutationUpdateManyQuery(r MutationUpdateManyRequest) error {
+	if err := checkDuplicateIDs(r.Rows, r.ColumnWithPrimaryKey); err != nil {
+		return err
+	}
+
+	return InTransaction(r.Ctx, r.DB, func(q Querier) error {
+		_, restrictionArgs := r.Policy.restrict(r.ColumnWithPrimaryKey)
+		// every statement also contains the arguments of the policy and of changing the version
+		statementVariables := len(restrictionArgs)
+		if r.Version != nil {
+			_, setArgs := r.Version.set()
+			statementVariables += len(setArgs)
+		}
+
+		for start := 0; start < len(r.Rows); {
+			variables := statementVariables
+			end := start
+			for end < len(r.Rows) {
+				rowVariables := 2*len(r.Rows[end]) - 1
+				if end > start && variables+rowVariables > maxVariables {
+					break
+				}
+				variables += rowVariables
+				end++
+			}
+
+			if err := updateRows(r, q, r.Rows[start:end]); err != nil {
+				return err
+			}
+
+			start = end
+		}
+
+		return nil
+	})
+}
+
+func updateRows(r MutationUpdateManyRequest, q Querier, rows []map[string]interface{}) error {
+	var columnNames []string
+	columnCases := map[string][]string{}
+	columnCaseValues := map[string][]interface{}{}
+	var versionCases []string
+	var versionCaseValues []interface{}
+	var ids []interface{}
+	for _, row := range rows {
+		id := row[r.ColumnWithPrimaryKey]
+		ids = append(ids, id)
+
+		for name, value := range row {
+			if name == r.ColumnWithPrimaryKey {
+				continue
+			}
+			if r.Version != nil && name == r.Version.Column {
+				versionExpr, versionArgs := r.Version.matches(value)
+				versionCases = append(versionCases, "WHEN ? THEN "+versionExpr)
+				versionCaseValues = append(append(versionCaseValues, id), versionArgs...)
+				continue
+			}
+			if _, ok := columnCases[name]; !ok {
+				columnNames = append(columnNames, name)
+			}
+
+			columnCases[name] = append(columnCases[name], "WHEN ? THEN ?")
+			columnCaseValues[name] = append(columnCaseValues[name], id, value)
+		}
+	}
+	sort.Strings(columnNames)
+
+	var columnExprs []string
+	var columnValues []interface{}
+	for _, name := range columnNames {
+		columnExprs = append(columnExprs, fmt.Sprintf("%s = CASE %s %s ELSE %s END", name, r.ColumnWithPrimaryKey, strings.Join(columnCases[name], " "), name))
+		columnValues = append(columnValues, columnCaseValues[name]...)
+	}
+	if r.Version != nil {
+		setExpr, setArgs := r.Version.set()
+		columnExprs = append(columnExprs, setExpr)
+		columnValues = append(columnValues, setArgs...)
+	}
+	if len(columnExprs) == 0 {
+		// rows without changes are only checked for their existence
+		columnExprs = append(columnExprs, fmt.Sprintf("%s = %s", r.ColumnWithPrimaryKey, r.ColumnWithPrimaryKey))
+	}
+
+	// the versions of the rows are compared per id
+	versionExpr := "1"
+	if len(versionCases) > 0 {
+		versionExpr = fmt.Sprintf("CASE %s %s ELSE 0 END", r.ColumnWithPrimaryKey, strings.Join(versionCases, " "))
+	}
+
+	restriction, restrictionArgs := r.Policy.restrict(r.ColumnWithPrimaryKey)
+
+	result, err := q.ExecContext(
+		r.Ctx,
+		fmt.Sprintf("UPDATE %s SET %s WHERE %s IN (%s) AND %s AND %s", r.Table, strings.Join(columnExprs, ", "), r.ColumnWithPrimaryKey, placeholders(len(ids)), versionExpr, restriction),
+		append(append(append(columnValues, ids...), versionCaseValues...), restrictionArgs...)...)
+	if err != nil {
+		return constraintError(err)
+	}
+
+	if err := checkAllRowsAffected(result, len(rows)); err != nil && r.Version != nil {
+		return checkConflict(r.Ctx, q, r.Table, r.ColumnWithPrimaryKey, ids, r.Version, r.Policy)
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// MutationDeleteManyRequest describes the query.
+type MutationDeleteManyRequest struct {
+	Ctx context.Context
+	DB  Querier
+
+	Table        string
+	ColumnName   string
+	ColumnValues []uint
+
+	// DeletedAt is the column of soft deletes, which is set to the current time instead of deleting the row.
+	DeletedAt string
+
+	// Policy restricts the deletion to accessible rows.
+	Policy *Policy
+}
+
+// MutationDeleteManyQuery deletes rows from the database.
+func MutationDeleteManyQuery(r MutationDeleteManyRequest) error {
+	rows := make([]map[string]interface{}, len(r.ColumnValues))
+	for i, value := range r.ColumnValues {
+		rows[i] = map[string]interface{}{r.ColumnName: value}
+	}
+	if err := checkDuplicateIDs(rows, r.ColumnName); err != nil {
+		return err
+	}
+
+	return InTransaction(r.Ctx, r.DB, func(q Querier) error {
+		restriction, restrictionArgs := r.Policy.restrict(r.ColumnName)
+
+		// one variable is left for the time of soft deletes
+		chunkSize := maxVariables - len(restrictionArgs) - 1
+		for start := 0; start < len(r.ColumnValues); start += chunkSize {
+			end := start + chunkSize
+			if end > len(r.ColumnValues) {
+				end = len(r.ColumnValues)
+			}
+
+			var ids []interface{}
+			for _, value := range r.ColumnValues[start:end] {
+				ids = append(ids, value)
+			}
+
+			statement, args := deleteStatement(
+				r.Table,
+				r.DeletedAt,
+				fmt.Sprintf("%s IN (%s) AND %s", r.ColumnName, placeholders(len(ids)), restriction),
+				append(ids, restrictionArgs...))
+			result, err := q.ExecContext(r.Ctx, statement, args...)
+			if err != nil {
+				return constraintError(err)
+			}
+
+			if err := checkAllRowsAffected(result, len(ids)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func checkDuplicateIDs(rows []map[string]interface{}, columnName string) error {
+	ids := map[interface{}]bool{}
+	for _, row := range rows {
+		if ids[row[columnName]] {
+			return errors.Errorf("duplicate id %v", row[columnName])
+		}
+		ids[row[columnName]] = true
+	}
+
+	return nil
+}
+
+// checkAllRowsAffected returns an error if a mutation of multiple rows did not affect all of them because a row does
+// not exist or is not accessible.
+func checkAllRowsAffected(result sql.Result, rows int) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected != int64(rows) {
+		return errors.Errorf("%d of %d rows not found", int64(rows)-rowsAffected, rows)
+	}
+
 	return nil
 }
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// MutationUpdateWhereRequest describes the query.
+type MutationUpdateWhereRequest struct {
+	Ctx context.Context
+	DB  Querier
+
+	Table        string
+	ColumnValues map[string]interface{}
+	Filter       Filter
+
+	// Version is changed in all updated rows, nil if the table has no version column.
+	Version *Version
+
+	// Policy restricts the update to accessible rows.
+	Policy *Policy
+}
+
+// MutationUpdateWhereQuery updates all rows matching the filter and returns their ids.
+func MutationUpdateWhereQuery(r MutationUpdateWhereRequest) ([]uint, error) {
+	var columnExprs []string
+	var columnValues []interface{}
+	for name, value := range r.ColumnValues {
+		columnExprs = append(columnExprs, fmt.Sprintf("%s = ?", name))
+		columnValues = append(columnValues, value)
+	}
+	if len(columnExprs) == 0 {
+		return nil, errors.New("missing values to update")
+	}
+	if r.Version != nil {
+		setExpr, setArgs := r.Version.set()
+		columnExprs = append(columnExprs, setExpr)
+		columnValues = append(columnValues, setArgs...)
+	}
+
+	var ids []uint
+	err := InTransaction(r.Ctx, r.DB, func(q Querier) error {
+		where, whereArgs := whereFilter(r.Filter, r.Policy)
+
+		var err error
+		if ids, err = queryIDs(r.Ctx, q, r.Table, where, whereArgs); err != nil {
+			return err
+		}
+
+		_, err = q.ExecContext(
+			r.Ctx,
+			fmt.Sprintf("UPDATE %s SET %s WHERE %s", r.Table, strings.Join(columnExprs, ", "), where),
+			append(columnValues, whereArgs...)...)
+		return err
+	})
+	if err != nil {
+		return nil, constraintError(err)
+	}
+
+	return ids, nil
+}
+
+// MutationDeleteWhereRequest describes the query.
+type MutationDeleteWhereRequest struct {
+	Ctx context.Context
+	DB  Querier
+
+	Table  string
+	Filter Filter
+
+	// DeletedAt is the column of soft deletes, which is set to the current time instead of deleting the row.
+	DeletedAt string
+
+	// Policy restricts the deletion to accessible rows.
+	Policy *Policy
+}
+
+// MutationDeleteWhereQuery deletes all rows matching the filter and returns their ids.
+func MutationDeleteWhereQuery(r MutationDeleteWhereRequest) ([]uint, error) {
+	var ids []uint
+	err := InTransaction(r.Ctx, r.DB, func(q Querier) error {
+		where, whereArgs := whereFilter(r.Filter, r.Policy)
+
+		var err error
+		if ids, err = queryIDs(r.Ctx, q, r.Table, where, whereArgs); err != nil {
+			return err
+		}
+
+		statement, args := deleteStatement(r.Table, r.DeletedAt, where, whereArgs)
+		_, err = q.ExecContext(r.Ctx, statement, args...)
+		return err
+	})
+	if err != nil {
+		return nil, constraintError(err)
+	}
+
+	return ids, nil
+}
+
+// whereFilter returns the expression of the filter restricted to accessible rows.
+func whereFilter(filter Filter, policy *Policy) (string, []interface{}) {
+	where, whereArgs := filter.where()
+	restriction, restrictionArgs := policy.restrict("id")
+
+	return where + " AND " + restriction, append(whereArgs, restrictionArgs...)
+}
+
+// queryIDs returns the ids of the rows matching the expression, which are affected by the following statement of the
+// transaction.
+func queryIDs(ctx context.Context, q Querier, table string, where string, args []interface{}) ([]uint, error) {
+	rows, err := q.QueryContext(ctx, fmt.Sprintf("SELECT id FROM %s WHERE %s ORDER BY id", table, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []uint{}
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
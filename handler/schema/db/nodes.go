@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// NodesRequest describes the query.
+type NodesRequest struct {
+	Ctx context.Context
+	DB  Querier
+
+	Table string
+	IDs   []uint
+
+	// Policy restricts the result to accessible rows.
+	Policy *Policy
+}
+
+// NodesQuery queries which of the ids exist in the database and returns them as set.
+func NodesQuery(r NodesRequest) (map[uint]bool, error) {
+	existing := map[uint]bool{}
+	if len(r.IDs) == 0 {
+		return existing, nil
+	}
+
+	restriction, restrictionArgs := r.Policy.restrict("id")
+
+	chunkSize := maxVariables - len(restrictionArgs)
+	for start := 0; start < len(r.IDs); start += chunkSize {
+		end := start + chunkSize
+		if end > len(r.IDs) {
+			end = len(r.IDs)
+		}
+
+		var ids []interface{}
+		for _, id := range r.IDs[start:end] {
+			ids = append(ids, id)
+		}
+
+		query := fmt.Sprintf("SELECT id FROM %s WHERE id IN (%s) AND %s", r.Table, placeholders(len(ids)), restriction)
+		if err := queryExisting(r, existing, query, append(ids, restrictionArgs...)); err != nil {
+			return nil, err
+		}
+	}
+
+	return existing, nil
+}
+
+// queryExisting adds the ids returned by the query to the set of existing ids.
+func queryExisting(r NodesRequest, existing map[uint]bool, query string, args []interface{}) error {
+	rows, err := r.DB.QueryContext(r.Ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var id uint
+	for rows.Next() {
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+
+		existing[id] = true
+	}
+
+	return rows.Err()
+}
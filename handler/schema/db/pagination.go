@@ -2,7 +2,6 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"strings"
 
@@ -47,17 +46,83 @@ type PaginationRequestJoinedMetadata struct {
 // IsPaginationRequestMetadata is used for interface constraining.
 func (PaginationRequestJoinedMetadata) IsPaginationRequestMetadata() {}
 
+// PaginationRequestRecursiveMetadata represents the metadata for recursive references within self-referencing
+// tables. Ancestors follow the ParentColumn from the row with ID towards the root, descendants follow it from the row
+// towards the leaves. Depth optionally limits the amount of levels.
+type PaginationRequestRecursiveMetadata struct {
+	Table        string
+	IDColumn     string
+	ParentColumn string
+	ID           interface{}
+	Ancestors    bool
+	Depth        *uint
+}
+
+// IsPaginationRequestMetadata is used for interface constraining.
+func (PaginationRequestRecursiveMetadata) IsPaginationRequestMetadata() {}
+
+// recursiveQuery builds a WITH RECURSIVE query selecting the ids of the ancestors or descendants ordered by depth.
+// The path of visited ids terminates the recursion on cyclic references.
+func recursiveQuery(metadata PaginationRequestRecursiveMetadata, policy *Policy) (string, []interface{}) {
+	var (
+		initial   string
+		recursive string
+		args      []interface{}
+	)
+	if metadata.Ancestors {
+		initial = fmt.Sprintf(
+			"SELECT %[2]s, 1, ',' || %[1]s || ',' || %[2]s || ',' FROM %[3]s WHERE %[1]s = ? AND %[2]s IS NOT NULL",
+			metadata.IDColumn, metadata.ParentColumn, metadata.Table)
+		recursive = fmt.Sprintf(
+			"SELECT t.%[2]s, tree.depth + 1, tree.path || t.%[2]s || ',' FROM %[3]s t JOIN tree ON t.%[1]s = tree.id WHERE t.%[2]s IS NOT NULL AND instr(tree.path, ',' || t.%[2]s || ',') = 0",
+			metadata.IDColumn, metadata.ParentColumn, metadata.Table)
+		args = []interface{}{metadata.ID}
+	} else {
+		initial = fmt.Sprintf(
+			"SELECT %[1]s, 1, ',' || ? || ',' || %[1]s || ',' FROM %[3]s WHERE %[2]s = ?",
+			metadata.IDColumn, metadata.ParentColumn, metadata.Table)
+		recursive = fmt.Sprintf(
+			"SELECT t.%[1]s, tree.depth + 1, tree.path || t.%[1]s || ',' FROM %[3]s t JOIN tree ON t.%[2]s = tree.id WHERE instr(tree.path, ',' || t.%[1]s || ',') = 0",
+			metadata.IDColumn, metadata.ParentColumn, metadata.Table)
+		args = []interface{}{metadata.ID, metadata.ID}
+	}
+
+	if metadata.Depth != nil {
+		recursive += " AND tree.depth < ?"
+		args = append(args, *metadata.Depth)
+	}
+
+	// the recursion follows inaccessible rows, but only accessible rows are returned
+	restriction, restrictionArgs := policy.restrict("id")
+
+	return fmt.Sprintf(
+		"WITH RECURSIVE tree(id, depth, path) AS (%s UNION ALL %s) SELECT id FROM tree WHERE %s ORDER BY depth",
+		initial, recursive, restriction,
+	), append(args, restrictionArgs...)
+}
+
+// PageSize contains the default page size used without first or last and the maximum of first and last, larger
+// pages are rejected. Zero values disable them.
+type PageSize struct {
+	Default uint
+	Max     uint
+}
+
 // PaginationRequest describes the query.
 type PaginationRequest struct {
 	Ctx context.Context
-	DB  *sql.DB
+	DB  Querier
 
 	Metadata PaginationRequestMetadata
+	// Policy restricts the returned ids to accessible rows.
+	Policy *Policy
 
 	Before *uint
 	After  *uint
 	First  *uint
 	Last   *uint
+
+	PageSize PageSize
 }
 
 // PaginationResult represents the response from a database pagination query.
@@ -91,6 +156,24 @@ func min(a, b uint) uint {
 
 // PaginationQuery queries the database and returns a page of result ids.
 func PaginationQuery(r PaginationRequest) PaginationResult {
+	if r.First == nil && r.Last == nil && r.PageSize.Default > 0 {
+		pageSize := r.PageSize.Default
+		if r.Before != nil && r.After == nil {
+			// the page ends at before
+			r.Last = &pageSize
+		} else {
+			r.First = &pageSize
+		}
+	}
+	if r.PageSize.Max > 0 {
+		if r.First != nil && *r.First > r.PageSize.Max {
+			return PaginationResult{Err: errors.Errorf("first %d exceeds the maximum page size of %d", *r.First, r.PageSize.Max)}
+		}
+		if r.Last != nil && *r.Last > r.PageSize.Max {
+			return PaginationResult{Err: errors.Errorf("last %d exceeds the maximum page size of %d", *r.Last, r.PageSize.Max)}
+		}
+	}
+
 	if r.First != nil && r.Last != nil {
 		// reset last
 		r.Last = nil
@@ -100,13 +183,19 @@ func PaginationQuery(r PaginationRequest) PaginationResult {
 	var args []interface{}
 	switch metadata := r.Metadata.(type) {
 	case PaginationRequestForwardMetadata:
-		query = fmt.Sprintf("SELECT %s FROM %s", metadata.Column, metadata.Table)
+		restriction, restrictionArgs := r.Policy.restrict(metadata.Column)
+		query = fmt.Sprintf("SELECT %s FROM %s WHERE %s", metadata.Column, metadata.Table, restriction)
+		args = restrictionArgs
 	case PaginationRequestBackwardMetadata:
-		query = fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?", metadata.ForeignReturnColumn, metadata.ForeignTable, metadata.ForeignReferenceColumn)
-		args = []interface{}{metadata.OwnReferenceColumn}
+		restriction, restrictionArgs := r.Policy.restrict(metadata.ForeignReturnColumn)
+		query = fmt.Sprintf("SELECT %s FROM %s WHERE %s = ? AND %s", metadata.ForeignReturnColumn, metadata.ForeignTable, metadata.ForeignReferenceColumn, restriction)
+		args = append([]interface{}{metadata.OwnReferenceColumn}, restrictionArgs...)
 	case PaginationRequestJoinedMetadata:
-		query = fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?", metadata.ForeignColumn, metadata.JoinTable, metadata.OwnColumn)
-		args = []interface{}{metadata.OwnValue}
+		restriction, restrictionArgs := r.Policy.restrict(metadata.ForeignColumn)
+		query = fmt.Sprintf("SELECT %s AS id FROM %s WHERE %s = ? AND %s", metadata.ForeignColumn, metadata.JoinTable, metadata.OwnColumn, restriction)
+		args = append([]interface{}{metadata.OwnValue}, restrictionArgs...)
+	case PaginationRequestRecursiveMetadata:
+		query, args = recursiveQuery(metadata, r.Policy)
 	default:
 		return PaginationResult{Err: errors.Errorf("unknown metadata type %T", metadata)}
 	}
@@ -0,0 +1,22 @@
+package db
+
+import (
+	"fmt"
+)
+
+// Policy restricts the accessible rows of a table with an SQL predicate and its arguments.
+type Policy struct {
+	Table     string
+	Predicate string
+	Args      []interface{}
+}
+
+// restrict returns an expression which is true if the id in the given column belongs to an accessible row. Without
+// policy all rows are accessible.
+func (p *Policy) restrict(column string) (string, []interface{}) {
+	if p == nil {
+		return "1", nil
+	}
+
+	return fmt.Sprintf("%s IN (SELECT id FROM %s WHERE %s)", column, p.Table, p.Predicate), p.Args
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestPolicyRestrict(t *testing.T) {
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	_, err = database.Exec(`CREATE TABLE tasks (id INTEGER PRIMARY KEY, owner_id TEXT, deleted_at TEXT);
+		INSERT INTO tasks (owner_id, deleted_at) VALUES ('1', NULL), ('2', NULL), ('1', '2020-01-01')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		policy     *Policy
+		expression string
+		ids        []int
+	}{
+		{
+			"no policy",
+			nil,
+			"1",
+			[]int{1, 2, 3},
+		},
+		{
+			"claim",
+			&Policy{Table: "tasks", Predicate: "owner_id = ?", Args: []interface{}{"1"}},
+			"id IN (SELECT id FROM tasks WHERE owner_id = ?)",
+			[]int{1, 3},
+		},
+		{
+			"missing claim",
+			&Policy{Table: "tasks", Predicate: "owner_id = ?", Args: []interface{}{nil}},
+			"id IN (SELECT id FROM tasks WHERE owner_id = ?)",
+			nil,
+		},
+		{
+			"combined predicate",
+			&Policy{Table: "tasks", Predicate: "owner_id = ? AND deleted_at IS NULL", Args: []interface{}{"1"}},
+			"id IN (SELECT id FROM tasks WHERE owner_id = ? AND deleted_at IS NULL)",
+			[]int{1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expression, args := test.policy.restrict("id")
+			if expression != test.expression {
+				t.Fatalf("expected %q, got %q", test.expression, expression)
+			}
+
+			rows, err := database.Query("SELECT id FROM tasks WHERE "+expression+" ORDER BY id", args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+
+			var ids []int
+			for rows.Next() {
+				var id int
+				if err := rows.Scan(&id); err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, id)
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(ids, test.ids) {
+				t.Fatalf("expected ids %v, got %v", test.ids, ids)
+			}
+		})
+	}
+}
+
+func TestAssociationPolicies(t *testing.T) {
+	database, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	_, err = database.Exec(`CREATE TABLE tags (id INTEGER PRIMARY KEY, public INTEGER);
+		CREATE TABLE post_tags (post_id INTEGER, tag_id INTEGER, PRIMARY KEY (post_id, tag_id));
+		INSERT INTO tags (public) VALUES (1), (0);
+		INSERT INTO post_tags VALUES (1, 2)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	public := &Policy{Table: "tags", Predicate: "public = ?", Args: []interface{}{1}}
+
+	tests := []struct {
+		name         string
+		disassociate bool
+		tagID        uint
+		policy       *Policy
+		err          bool
+		associated   bool
+	}{
+		{"associate accessible row", false, 1, public, false, true},
+		{"associate inaccessible row", false, 2, public, true, true},
+		{"associate existing row without policy", false, 2, nil, true, true},
+		{"disassociate inaccessible row", true, 2, public, false, true},
+		{"disassociate accessible row", true, 1, public, false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			columns := map[string]interface{}{"post_id": 1, "tag_id": test.tagID}
+			policies := map[string]*Policy{"tag_id": test.policy}
+
+			var err error
+			if test.disassociate {
+				err = MutationDisassociateQuery(MutationDisassociateRequest{Ctx: context.Background(), DB: database, Table: "post_tags", ColumnValues: columns, Policies: policies})
+			} else {
+				err = MutationAssociateQuery(MutationAssociateRequest{Ctx: context.Background(), DB: database, Table: "post_tags", ColumnValues: columns, Policies: policies})
+			}
+			if (err != nil) != test.err {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			var count int
+			if err := database.QueryRow("SELECT COUNT(*) FROM post_tags WHERE post_id = 1 AND tag_id = ?", test.tagID).Scan(&count); err != nil {
+				t.Fatal(err)
+			}
+			if (count == 1) != test.associated {
+				t.Fatalf("expected associated %t, got %d rows", test.associated, count)
+			}
+		})
+	}
+}
@@ -4,17 +4,54 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
 )
 
 // ScalarRequest describes the query.
 type ScalarRequest struct {
 	Ctx context.Context
-	DB  *sql.DB
+	DB  Querier
 
 	Table  string
 	Column string
 
 	ID uint
+	// Keys identify the row by multiple columns instead of the ID (e.g. in join tables).
+	Keys map[string]interface{}
+
+	// Policy restricts the query to accessible rows, inaccessible rows result in sql.ErrNoRows.
+	Policy *Policy
+}
+
+func (r ScalarRequest) query() (string, []interface{}) {
+	restriction, restrictionArgs := r.Policy.restrict("id")
+
+	if len(r.Keys) == 0 {
+		return fmt.Sprintf("SELECT %s FROM %s WHERE id = ? AND %s", r.Column, r.Table, restriction), append([]interface{}{r.ID}, restrictionArgs...)
+	}
+
+	var keyNames []string
+	for name := range r.Keys {
+		keyNames = append(keyNames, name)
+	}
+	sort.Strings(keyNames)
+
+	var whereExprs []string
+	var whereValues []interface{}
+	for _, name := range keyNames {
+		whereExprs = append(whereExprs, fmt.Sprintf("%s = ?", name))
+		whereValues = append(whereValues, r.Keys[name])
+	}
+
+	whereExprs = append(whereExprs, restriction)
+	whereValues = append(whereValues, restrictionArgs...)
+
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s", r.Column, r.Table, strings.Join(whereExprs, " AND ")), whereValues
 }
 
 // ScalarIntQuery queries the database and returns a integer.
@@ -22,7 +59,8 @@ func ScalarIntQuery(r ScalarRequest) (interface{}, error) {
 	var (
 		value sql.NullInt64
 	)
-	if err := r.DB.QueryRowContext(r.Ctx, fmt.Sprintf("SELECT %s FROM %s WHERE id = ?", r.Column, r.Table), r.ID).Scan(&value); err != nil {
+	query, args := r.query()
+	if err := r.DB.QueryRowContext(r.Ctx, query, args...).Scan(&value); err != nil {
 		return nil, err
 	}
 
@@ -38,7 +76,8 @@ func ScalarFloatQuery(r ScalarRequest) (interface{}, error) {
 	var (
 		value sql.NullFloat64
 	)
-	if err := r.DB.QueryRowContext(r.Ctx, fmt.Sprintf("SELECT %s FROM %s WHERE id = ?", r.Column, r.Table), r.ID).Scan(&value); err != nil {
+	query, args := r.query()
+	if err := r.DB.QueryRowContext(r.Ctx, query, args...).Scan(&value); err != nil {
 		return nil, err
 	}
 
@@ -54,7 +93,8 @@ func ScalarStringQuery(r ScalarRequest) (interface{}, error) {
 	var (
 		value sql.NullString
 	)
-	if err := r.DB.QueryRowContext(r.Ctx, fmt.Sprintf("SELECT %s FROM %s WHERE id = ?", r.Column, r.Table), r.ID).Scan(&value); err != nil {
+	query, args := r.query()
+	if err := r.DB.QueryRowContext(r.Ctx, query, args...).Scan(&value); err != nil {
 		return nil, err
 	}
 
@@ -70,7 +110,8 @@ func ScalarBooleanQuery(r ScalarRequest) (interface{}, error) {
 	var (
 		value sql.NullBool
 	)
-	if err := r.DB.QueryRowContext(r.Ctx, fmt.Sprintf("SELECT %s FROM %s WHERE id = ?", r.Column, r.Table), r.ID).Scan(&value); err != nil {
+	query, args := r.query()
+	if err := r.DB.QueryRowContext(r.Ctx, query, args...).Scan(&value); err != nil {
 		return nil, err
 	}
 
@@ -84,15 +125,37 @@ func ScalarBooleanQuery(r ScalarRequest) (interface{}, error) {
 // ScalarDateTimeQuery queries the database and returns a date-time.
 func ScalarDateTimeQuery(r ScalarRequest) (interface{}, error) {
 	var (
-		value sql.NullTime
+		value interface{}
 	)
-	if err := r.DB.QueryRowContext(r.Ctx, fmt.Sprintf("SELECT %s FROM %s WHERE id = ?", r.Column, r.Table), r.ID).Scan(&value); err != nil {
+	query, args := r.query()
+	if err := r.DB.QueryRowContext(r.Ctx, query, args...).Scan(&value); err != nil {
 		return nil, err
 	}
 
-	if !value.Valid {
+	return parseDateTime(value)
+}
+
+// parseDateTime converts a value of a column into a date-time. Columns without date-time type (e.g. TEXT columns
+// with forced type) are returned as strings or integers by the driver and need to be parsed.
+func parseDateTime(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case nil:
 		return nil, nil
+	case time.Time:
+		return value, nil
+	case int64:
+		return time.Unix(value, 0).UTC(), nil
+	case []byte:
+		return parseDateTime(string(value))
+	case string:
+		for _, format := range sqlite3.SQLiteTimestampFormats {
+			if t, err := time.ParseInLocation(format, strings.TrimSuffix(value, "Z"), time.UTC); err == nil {
+				return t, nil
+			}
+		}
+
+		return nil, errors.Errorf("malformed date-time '%s'", value)
 	}
 
-	return value.Time, nil
+	return nil, errors.Errorf("unsupported date-time value %T", value)
 }
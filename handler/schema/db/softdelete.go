@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// deleteStatement returns the statement deleting the rows matching where. Rows of tables with soft deletes are
+// marked as deleted instead.
+func deleteStatement(table string, deletedAt string, where string, args []interface{}) (string, []interface{}) {
+	if deletedAt == "" {
+		return fmt.Sprintf("DELETE FROM %s WHERE %s", table, where), args
+	}
+
+	return fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s", table, deletedAt, where), append([]interface{}{time.Now().UTC()}, args...)
+}
+
+// MutationRestoreRequest describes the query.
+type MutationRestoreRequest struct {
+	Ctx context.Context
+	DB  Querier
+
+	Table       string
+	ColumnName  string
+	ColumnValue interface{}
+	DeletedAt   string
+
+	// Policy restricts the restoration to accessible rows.
+	Policy *Policy
+}
+
+// MutationRestoreQuery restores a soft-deleted row.
+func MutationRestoreQuery(r MutationRestoreRequest) error {
+	restriction, restrictionArgs := r.Policy.restrict(r.ColumnName)
+
+	result, err := r.DB.ExecContext(
+		r.Ctx,
+		fmt.Sprintf("UPDATE %s SET %s = NULL WHERE %s = ? AND %s IS NOT NULL AND %s", r.Table, r.DeletedAt, r.ColumnName, r.DeletedAt, restriction),
+		append([]interface{}{r.ColumnValue}, restrictionArgs...)...)
+	if err != nil {
+		return constraintError(err)
+	}
+
+	return checkRowsAffected(result)
+}
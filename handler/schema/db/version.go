@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Version is the column of optimistic concurrency control. Updates only change rows whose version still has the
+// value read by the client and change the version.
+type Version struct {
+	Column string
+	// Increment increments integer versions, other versions (e.g. updated_at) are set to the current time.
+	Increment bool
+}
+
+// Initial returns the version of created rows.
+func (v *Version) Initial() interface{} {
+	if v.Increment {
+		return 1
+	}
+
+	return time.Now().UTC()
+}
+
+// set returns the expression changing the version.
+func (v *Version) set() (string, []interface{}) {
+	if v.Increment {
+		return fmt.Sprintf("%s = COALESCE(%s, 0) + 1", v.Column, v.Column), nil
+	}
+
+	return fmt.Sprintf("%s = ?", v.Column), []interface{}{time.Now().UTC()}
+}
+
+// matches returns the condition comparing the version to the expected value. Date-times are compared by their time
+// instead of their textual representation.
+func (v *Version) matches(expected interface{}) (string, []interface{}) {
+	if v.Increment {
+		return fmt.Sprintf("%s = ?", v.Column), []interface{}{expected}
+	}
+
+	return fmt.Sprintf("julianday(%s) = julianday(?)", v.Column), []interface{}{expected}
+}
+
+// ConflictError is returned if the version of an updated row does not match because the row was changed since the
+// client read it.
+type ConflictError struct {
+	Table  string
+	Column string
+}
+
+func (e ConflictError) Error() string {
+	return fmt.Sprintf("%s.%s does not match, the row was changed concurrently", e.Table, e.Column)
+}
+
+// checkConflict returns a conflict error if all rows with the ids exist, which were not updated because of their
+// version. Otherwise a row was not found.
+func checkConflict(ctx context.Context, q Querier, table string, columnID string, ids []interface{}, version *Version, policy *Policy) error {
+	restriction, restrictionArgs := policy.restrict(columnID)
+
+	var count int
+	if err := q.QueryRowContext(
+		ctx,
+		fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s IN (%s) AND %s", table, columnID, placeholders(len(ids)), restriction),
+		append(append([]interface{}{}, ids...), restrictionArgs...)...).Scan(&count); err != nil {
+		return err
+	}
+	if count < len(ids) && len(ids) == 1 {
+		return errors.New("row not found")
+	}
+	if count < len(ids) {
+		return errors.Errorf("%d of %d rows not found", len(ids)-count, len(ids))
+	}
+
+	return ConflictError{Table: table, Column: version.Column}
+}
@@ -0,0 +1,239 @@
+package schema
+
+import (
+	"context"
+	"dynamic-graphql-api/handler/schema/db"
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/pkg/errors"
+)
+
+// filterOperators maps the operators of filter inputs to SQL operators.
+var filterOperators = map[string]string{
+	"eq":    "=",
+	"ne":    "<>",
+	"lt":    "<",
+	"lte":   "<=",
+	"gt":    ">",
+	"gte":   ">=",
+	"in":    "IN",
+	"notIn": "NOT IN",
+}
+
+// scalarFilters maps scalar type names to their filter inputs (e.g. IntFilter).
+var scalarFilters = map[string]*graphql.InputObject{
+	"Int":      newScalarFilter(graphql.Int, true),
+	"Float":    newScalarFilter(graphql.Float, true),
+	"String":   newScalarFilter(graphql.String, true),
+	"Boolean":  newScalarFilter(graphql.Boolean, false),
+	"ID":       newScalarFilter(graphql.ID, false),
+	"DateTime": newScalarFilter(graphql.DateTime, true),
+}
+
+func newScalarFilter(t *graphql.Scalar, comparable bool) *graphql.InputObject {
+	fields := graphql.InputObjectConfigFieldMap{
+		"eq":    &graphql.InputObjectFieldConfig{Type: t},
+		"ne":    &graphql.InputObjectFieldConfig{Type: t},
+		"in":    &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(t))},
+		"notIn": &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(t))},
+		"isNull": &graphql.InputObjectFieldConfig{
+			Type:        graphql.Boolean,
+			Description: "Whether the value is null (true) or not null (false).",
+		},
+	}
+	if comparable {
+		fields["lt"] = &graphql.InputObjectFieldConfig{Type: t}
+		fields["lte"] = &graphql.InputObjectFieldConfig{Type: t}
+		fields["gt"] = &graphql.InputObjectFieldConfig{Type: t}
+		fields["gte"] = &graphql.InputObjectFieldConfig{Type: t}
+	}
+
+	return graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:   t.Name() + "Filter",
+		Fields: fields,
+	})
+}
+
+type filterField struct {
+	column string
+	// referencedObjectName is the object of IDs, which are compared by their database id.
+	referencedObjectName string
+}
+
+// newObjectFilter creates the filter input of an object (e.g. UserFilter) from the scalar and forward reference
+// fields of its mutations. All conditions of a filter have to match, and and or combine nested filters.
+func newObjectFilter(objName string, mutationFields map[string]mutationField) (*graphql.InputObject, map[string]filterField) {
+	filterFields := map[string]filterField{}
+	inputFields := graphql.InputObjectConfigFieldMap{}
+	for name, fieldDefinition := range mutationFields {
+		fieldConfig := fieldDefinition.fieldConfigUpdate
+		if fieldConfig == nil {
+			fieldConfig = fieldDefinition.fieldConfigCreate
+		}
+
+		var valueType string
+		switch {
+		case fieldConfig != nil:
+			valueType = strings.TrimSuffix(fieldConfig.Type.Name(), "!")
+		case fieldDefinition.managedTimestamp != "":
+			// managed timestamps are not part of inputs, but can be filtered
+			valueType = "DateTime"
+		default:
+			continue
+		}
+
+		scalarFilter, ok := scalarFilters[valueType]
+		if !ok {
+			continue
+		}
+
+		field := filterField{column: fieldDefinition.column}
+		if valueType == "ID" {
+			field.referencedObjectName = fieldDefinition.referencedObjectName
+			if fieldDefinition.isPrimaryKey {
+				field.referencedObjectName = objName
+			}
+		}
+
+		filterFields[name] = field
+		inputFields[name] = &graphql.InputObjectFieldConfig{Type: scalarFilter}
+	}
+
+	var filter *graphql.InputObject
+	filter = graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: objName + "Filter",
+		Fields: graphql.InputObjectConfigFieldMapThunk(func() graphql.InputObjectConfigFieldMap {
+			inputFields["and"] = &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewList(graphql.NewNonNull(filter)),
+				Description: "All of the filters have to match.",
+			}
+			inputFields["or"] = &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewList(graphql.NewNonNull(filter)),
+				Description: "Any of the filters has to match.",
+			}
+
+			return inputFields
+		}),
+	})
+
+	return filter, filterFields
+}
+
+// getFilter converts the input of an object filter to a filter of its table. Filtering by a column requires the
+// permission to read it.
+func getFilter(ctx context.Context, table string, fields map[string]filterField, input map[string]interface{}) (db.Filter, error) {
+	filter := db.Filter{And: []db.Filter{}}
+	for name, value := range input {
+		if name == "and" || name == "or" {
+			values, ok := value.([]interface{})
+			if !ok {
+				return filter, errors.Errorf("malformed filter %s", name)
+			}
+
+			filters := []db.Filter{}
+			for _, value := range values {
+				nestedInput, ok := value.(map[string]interface{})
+				if !ok {
+					return filter, errors.Errorf("malformed filter %s", name)
+				}
+				nestedFilter, err := getFilter(ctx, table, fields, nestedInput)
+				if err != nil {
+					return filter, err
+				}
+				filters = append(filters, nestedFilter)
+			}
+
+			if name == "and" {
+				filter.And = append(filter.And, db.Filter{And: filters})
+			} else {
+				filter.And = append(filter.And, db.Filter{Or: filters})
+			}
+			continue
+		}
+
+		field, ok := fields[name]
+		if !ok {
+			return filter, errors.Errorf("unexpected filter field %s", name)
+		}
+		if err := checkColumn(ctx, table, field.column, "read"); err != nil {
+			return filter, err
+		}
+
+		operations, ok := value.(map[string]interface{})
+		if !ok {
+			return filter, errors.Errorf("malformed filter of field %s", name)
+		}
+		for operator, operand := range operations {
+			condition, err := field.condition(name, operator, operand)
+			if err != nil {
+				return filter, err
+			}
+			filter.And = append(filter.And, condition)
+		}
+	}
+
+	return filter, nil
+}
+
+// condition converts an operation of a field filter (e.g. gt: 5) to a filter of its column.
+func (f filterField) condition(name string, operator string, operand interface{}) (db.Filter, error) {
+	if operator == "isNull" {
+		isNull, ok := operand.(bool)
+		if !ok {
+			return db.Filter{}, errors.Errorf("malformed isNull filter of field %s", name)
+		}
+		if isNull {
+			return db.Filter{Column: f.column, Operator: "IS NULL"}, nil
+		}
+		return db.Filter{Column: f.column, Operator: "IS NOT NULL"}, nil
+	}
+
+	sqlOperator, ok := filterOperators[operator]
+	if !ok {
+		return db.Filter{}, errors.Errorf("unsupported filter %s of field %s", operator, name)
+	}
+
+	if operator == "in" || operator == "notIn" {
+		operands, ok := operand.([]interface{})
+		if !ok {
+			return db.Filter{}, errors.Errorf("malformed %s filter of field %s", operator, name)
+		}
+
+		values := []interface{}{}
+		for _, operand := range operands {
+			value, err := f.value(name, operand)
+			if err != nil {
+				return db.Filter{}, err
+			}
+			values = append(values, value)
+		}
+
+		return db.Filter{Column: f.column, Operator: sqlOperator, Value: values}, nil
+	}
+
+	value, err := f.value(name, operand)
+	if err != nil {
+		return db.Filter{}, err
+	}
+
+	return db.Filter{Column: f.column, Operator: sqlOperator, Value: value}, nil
+}
+
+// value converts IDs to their database ids, other values are compared as they are.
+func (f filterField) value(name string, operand interface{}) (interface{}, error) {
+	if f.referencedObjectName == "" {
+		return operand, nil
+	}
+
+	c, err := parseCursor(fmt.Sprintf("%v", operand))
+	if err != nil {
+		return nil, err
+	}
+	if c.object != f.referencedObjectName {
+		return nil, errors.Errorf("unexpected id type %s of field %s (expected %s)", c.object, name, f.referencedObjectName)
+	}
+
+	return c.id, nil
+}
@@ -0,0 +1,296 @@
+package graph
+
+import (
+	"dynamic-graphql-api/handler/config"
+
+	"github.com/jinzhu/inflection"
+	"github.com/pkg/errors"
+)
+
+// ApplyInflectionConfig registers the configured pluralization overrides. It must be called before names are
+// generated.
+func ApplyInflectionConfig(c *config.Config) {
+	for _, word := range c.Inflection.Uncountable {
+		inflection.AddUncountable(word)
+	}
+	for singular, plural := range c.Inflection.Irregular {
+		inflection.AddIrregular(singular, plural)
+	}
+}
+
+func (g *Graph) tableColumns(table *Node) Nodes {
+	return g.Edges().FilterSource(table).FilterEdgeType("tableHasColumn").Targets()
+}
+
+// ApplyTableConfig hides tables and columns and marks join tables as configured. It must be called after the
+// foreign key references were added and before the objects are added.
+func (g *Graph) ApplyTableConfig(c *config.Config) error {
+	for tableName, tableConfig := range c.Tables {
+		table := g.Nodes().FilterTables().FilterName(tableName).First()
+		if table == nil {
+			return errors.Errorf("configured table %s does not exist", tableName)
+		}
+
+		for columnName := range tableConfig.Columns {
+			if g.tableColumns(table).FilterName(columnName).Len() != 1 {
+				return errors.Errorf("configured column %s.%s does not exist", tableName, columnName)
+			}
+		}
+
+		if tableConfig.Hidden {
+			g.hideTable(table)
+			continue
+		}
+
+		if tableConfig.JoinTable != nil {
+			table.Attrs["isJoinTable"] = "false"
+			if *tableConfig.JoinTable {
+				table.Attrs["isJoinTable"] = "true"
+			}
+		}
+
+		for columnName, columnConfig := range tableConfig.Columns {
+			if columnConfig.Hidden {
+				g.hideColumn(g.tableColumns(table).FilterName(columnName).First())
+			}
+		}
+	}
+
+	return nil
+}
+
+func (g *Graph) hideTable(table *Node) {
+	g.tableColumns(table).ForEach(func(column *Node) bool {
+		g.hideColumn(column)
+		return true
+	})
+
+	g.removeNode(table)
+}
+
+func (g *Graph) hideColumn(column *Node) {
+	// columns referencing the hidden column become regular columns
+	g.Edges().FilterTarget(column).FilterEdgeType("foreignKeyReferenceColumn").Sources().ForEach(func(referencingColumn *Node) bool {
+		g.Edges().FilterSource(referencingColumn).FilterEdgeType("foreignKeyReferenceTable").Targets().ForEach(func(referencedTable *Node) bool {
+			g.removeEdges(referencingColumn, referencedTable)
+			return true
+		})
+		referencingColumn.Attrs["foreignKeyTable"] = ""
+		referencingColumn.Attrs["foreignKeyColumn"] = ""
+
+		return true
+	})
+
+	// unique keys containing the hidden column cannot be used anymore
+	g.Edges().FilterTarget(column).FilterEdgeType("uniqueKeyHasColumn").Sources().ForEach(func(uniqueKey *Node) bool {
+		g.removeNode(uniqueKey)
+		return true
+	})
+
+	g.removeNode(column)
+}
+
+// ApplyObjectConfig renames objects and fields and forces scalar types as configured. It must be called after the
+// objects are added.
+func (g *Graph) ApplyObjectConfig(c *config.Config) error {
+	for tableName, tableConfig := range c.Tables {
+		if tableConfig.Hidden {
+			continue
+		}
+
+		table := g.Nodes().FilterTables().FilterName(tableName).First()
+		if table == nil {
+			return errors.Errorf("configured table %s does not exist", tableName)
+		}
+		object := g.Edges().FilterTarget(table).FilterEdgeType("objectHasTable").Sources().First()
+		if object == nil {
+			if tableConfig.Name != "" || tableConfig.Plural != "" || len(tableConfig.Fields) > 0 {
+				return errors.Errorf("configured table %s has no object (join table?)", tableName)
+			}
+
+			// columns of join tables are edge fields
+			for columnName, columnConfig := range tableConfig.Columns {
+				column := g.tableColumns(table).FilterName(columnName).First()
+				edgeField := g.Edges().FilterTarget(column).FilterEdgeType("fieldHasColumn").Sources().Filter(func(field *Node) bool {
+					return field.HasAttrKey("valueType")
+				}).First()
+				if edgeField == nil {
+					continue
+				}
+				if err := applyColumnConfig(tableName, edgeField, column, columnConfig); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		if tableConfig.Name != "" {
+			object.Attrs["name"] = tableConfig.Name
+			object.Attrs["pluralName"] = inflection.Plural(tableConfig.Name)
+		}
+		if tableConfig.Plural != "" {
+			object.Attrs["pluralName"] = tableConfig.Plural
+		}
+
+		var err error
+		g.Edges().FilterSource(object).FilterEdgeType("objectHasField").Targets().ForEach(func(field *Node) bool {
+			if newName, ok := tableConfig.Fields[field.GetAttrValueDefault("name", "")]; ok {
+				field.Attrs["name"] = newName
+			}
+
+			if field.HasAttrKey("referenceType") && !field.HasAttrValue("referenceType", "forward") {
+				return true
+			}
+
+			column := g.Edges().FilterSource(field).FilterEdgeType("fieldHasColumn").Targets().First()
+			if column == nil {
+				return true
+			}
+			columnConfig, ok := tableConfig.Columns[column.GetAttrValueDefault("name", "")]
+			if !ok {
+				return true
+			}
+
+			err = applyColumnConfig(tableName, field, column, columnConfig)
+			return err == nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for columnName, columnConfig := range tableConfig.Columns {
+			if columnConfig.BackReference == "" {
+				continue
+			}
+
+			column := g.tableColumns(table).FilterName(columnName).First()
+			backReferences := g.Edges().FilterTarget(column).FilterEdgeType("fieldReferencesColumn").Sources().Filter(func(field *Node) bool {
+				return field.HasAttrValue("referenceType", "backward")
+			})
+			if backReferences.Len() != 1 {
+				return errors.Errorf("configured column %s.%s has no back-reference", tableName, columnName)
+			}
+			backReferences.First().Attrs["name"] = columnConfig.BackReference
+		}
+	}
+
+	return g.checkNames()
+}
+
+// applyColumnConfig renames the field generated from a column and forces its type.
+func applyColumnConfig(tableName string, field *Node, column *Node, columnConfig config.Column) error {
+	if columnConfig.Name != "" {
+		field.Attrs["name"] = columnConfig.Name
+	}
+
+	if columnConfig.Type != "" {
+		if !field.HasAttrKey("valueType") || column.HasAttrValue("isPrimaryKey", "true") {
+			return errors.Errorf("type of column %s.%s cannot be forced", tableName, column.GetAttrValueDefault("name", ""))
+		}
+
+		switch columnConfig.Type {
+		case "Int", "Float", "String", "Boolean", "DateTime":
+		default:
+			return errors.Errorf("unsupported forced type %s of column %s.%s", columnConfig.Type, tableName, column.GetAttrValueDefault("name", ""))
+		}
+
+		valueType := columnConfig.Type
+		if column.HasAttrValue("isNonNull", "true") {
+			valueType += "!"
+		}
+		field.Attrs["valueType"] = valueType
+	}
+
+	return nil
+}
+
+// ApplyTimestampConfig marks the fields of the created_at, updated_at and deleted_at columns (or the configured
+// columns) as managed timestamps of type DateTime, which are set by the server. It must be called after the object
+// config was applied.
+func (g *Graph) ApplyTimestampConfig(c *config.Config) error {
+	var err error
+	g.Nodes().FilterObjects().ForEach(func(object *Node) bool {
+		table := g.Edges().FilterSource(object).FilterEdgeType("objectHasTable").Targets().First()
+		if table == nil {
+			return true
+		}
+		tableName := table.GetAttrValueDefault("name", "")
+		tableConfig := c.Tables[tableName]
+
+		for _, timestamp := range []struct {
+			kind   string
+			column string
+			config string
+		}{
+			{kind: "created", column: "created_at", config: tableConfig.CreatedAt},
+			{kind: "updated", column: "updated_at", config: tableConfig.UpdatedAt},
+			{kind: "deleted", column: "deleted_at", config: tableConfig.DeletedAt},
+		} {
+			columnName := timestamp.column
+			if timestamp.config != "" {
+				columnName = timestamp.config
+			}
+			// the version column is changed by updates itself
+			if columnName == "-" || columnName == tableConfig.Version {
+				continue
+			}
+
+			column := g.tableColumns(table).FilterName(columnName).First()
+			var field *Node
+			if column != nil && !column.HasAttrValue("isPrimaryKey", "true") {
+				field = g.Edges().FilterTarget(column).FilterEdgeType("fieldHasColumn").Sources().Filter(func(field *Node) bool {
+					return field.HasAttrKey("valueType")
+				}).First()
+			}
+			if field == nil {
+				if timestamp.config != "" {
+					err = errors.Errorf("configured timestamp column %s.%s does not exist", tableName, columnName)
+					return false
+				}
+				continue
+			}
+
+			valueType := "DateTime"
+			if column.HasAttrValue("isNonNull", "true") {
+				valueType += "!"
+			}
+			field.Attrs["valueType"] = valueType
+			field.Attrs["managedTimestamp"] = timestamp.kind
+		}
+
+		return true
+	})
+
+	return err
+}
+
+// checkNames ensures that object names are unique and that field names are unique within their objects.
+func (g *Graph) checkNames() error {
+	objectNames := map[string]bool{}
+	var err error
+	g.Nodes().FilterObjects().ForEach(func(object *Node) bool {
+		objectName := object.GetAttrValueDefault("name", "")
+		if objectNames[objectName] {
+			err = errors.Errorf("object %s already exists", objectName)
+			return false
+		}
+		objectNames[objectName] = true
+
+		fieldNames := map[string]bool{}
+		g.Edges().FilterSource(object).FilterEdgeType("objectHasField").Targets().ForEach(func(field *Node) bool {
+			fieldName := field.GetAttrValueDefault("name", "")
+			if fieldNames[fieldName] {
+				err = errors.Errorf("field %s already exists in object %s", fieldName, objectName)
+				return false
+			}
+			fieldNames[fieldName] = true
+
+			return true
+		})
+
+		return err == nil
+	})
+
+	return err
+}
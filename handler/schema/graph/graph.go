@@ -1,6 +1,8 @@
 package graph
 
 import (
+	"dynamic-graphql-api/handler/config"
+	"dynamic-graphql-api/handler/schema/db"
 	"fmt"
 	"strings"
 )
@@ -11,21 +13,34 @@ type Graph struct {
 	edges []*Edge
 }
 
-// NewGraph creates a new graph based on SQL statements.
-func NewGraph(sqls []string) (*Graph, error) {
+// NewGraph creates a new graph based on SQL statements and unique indexes and applies the configuration to it.
+func NewGraph(sqls []string, uniqueIndexes []db.UniqueIndex, c *config.Config) (*Graph, error) {
 	g := &Graph{}
+	ApplyInflectionConfig(c)
 	if err := g.AddStmts(sqls); err != nil {
 		return nil, err
 	}
+	if err := g.AddUniqueIndexes(uniqueIndexes); err != nil {
+		return nil, err
+	}
 	if err := g.AddForeignKeyReferences(); err != nil {
 		return nil, err
 	}
+	if err := g.ApplyTableConfig(c); err != nil {
+		return nil, err
+	}
 	if err := g.MarkJoinTables(); err != nil {
 		return nil, err
 	}
 	if err := g.AddObjects(); err != nil {
 		return nil, err
 	}
+	if err := g.ApplyObjectConfig(c); err != nil {
+		return nil, err
+	}
+	if err := g.ApplyTimestampConfig(c); err != nil {
+		return nil, err
+	}
 
 	return g, nil
 }
@@ -219,6 +234,35 @@ func (g *Graph) addEdge(from, to *Node, attrs map[string]string) *Edge {
 	return e
 }
 
+func (g *Graph) removeNode(n *Node) {
+	var nodes []*Node
+	for _, node := range g.nodes {
+		if node != n {
+			nodes = append(nodes, node)
+		}
+	}
+	g.nodes = nodes
+
+	// remove all edges from and to the removed node
+	var edges []*Edge
+	for _, e := range g.edges {
+		if e.From != n && e.To != n {
+			edges = append(edges, e)
+		}
+	}
+	g.edges = edges
+}
+
+func (g *Graph) removeEdges(from, to *Node) {
+	var edges []*Edge
+	for _, e := range g.edges {
+		if e.From != from || e.To != to {
+			edges = append(edges, e)
+		}
+	}
+	g.edges = edges
+}
+
 // Node is a node in a graph which has attributes.
 type Node struct {
 	Attrs map[string]string
@@ -1,6 +1,10 @@
 package graph
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/iancoleman/strcase"
 	"github.com/jinzhu/inflection"
 	"github.com/pkg/errors"
@@ -16,7 +20,54 @@ func (n Nodes) FilterFields() Nodes {
 	return n.FilterNodeType("field")
 }
 
+// uniqueFieldName returns the first candidate which is neither a field of the object nor reserved. If all candidates
+// are taken a numeric suffix is appended to the last candidate.
+func (g *Graph) uniqueFieldName(object *Node, reserved map[string]bool, candidates ...string) string {
+	fields := g.Edges().FilterSource(object).FilterEdgeType("objectHasField").Targets()
+	isTaken := func(name string) bool {
+		return reserved[name] || fields.FilterName(name).Len() > 0
+	}
+
+	for _, candidate := range candidates {
+		if !isTaken(candidate) {
+			return candidate
+		}
+	}
+
+	last := candidates[len(candidates)-1]
+	for i := 2; ; i++ {
+		if candidate := fmt.Sprintf("%s%d", last, i); !isTaken(candidate) {
+			return candidate
+		}
+	}
+}
+
+// referenceName derives the name of a reference from a foreign key column by removing the id suffix (e.g. sender_id
+// becomes sender). Columns without suffix are named after the referenced table.
+func referenceName(column *Node, referencedTable *Node) string {
+	name := strings.TrimSuffix(strcase.ToLowerCamel(column.GetAttrValueDefault("name", "")), "Id")
+	if name == "" || name == "id" {
+		return inflection.Singular(strcase.ToLowerCamel(referencedTable.GetAttrValueDefault("name", "")))
+	}
+
+	return name
+}
+
+// countReferences counts the foreign key columns of a table referencing another table.
+func (g *Graph) countReferences(table *Node, referencedTable *Node) int {
+	return g.Edges().FilterSource(table).FilterEdgeType("tableHasColumn").Targets().Filter(func(column *Node) bool {
+		return g.Edges().FilterSource(column).FilterEdgeType("foreignKeyReferenceTable").FilterTarget(referencedTable).Len() > 0
+	}).Len()
+}
+
 func (g *Graph) addObjectDirectFields(table *Node, object *Node) error {
+	// names of scalar fields are reserved so that forward references do not collide with them
+	reserved := map[string]bool{}
+	g.Edges().FilterSource(table).FilterEdgeType("tableHasColumn").Targets().ForEach(func(column *Node) bool {
+		reserved[strcase.ToLowerCamel(column.GetAttrValueDefault("name", ""))] = true
+		return true
+	})
+
 	var err error
 	g.Edges().FilterSource(table).FilterEdgeType("tableHasColumn").Targets().ForEach(func(column *Node) bool {
 		foreignKeyTables := g.Edges().FilterSource(column).FilterEdgeType("foreignKeyReferenceTable")
@@ -31,9 +82,12 @@ func (g *Graph) addObjectDirectFields(table *Node, object *Node) error {
 				return false
 			}
 
+			fieldName := g.uniqueFieldName(object, reserved,
+				referenceName(column, foreignKeyTable),
+				strcase.ToLowerCamel(column.GetAttrValueDefault("name", "")+"_"+foreignKeyTable.GetAttrValueDefault("name", "")))
 			field := g.addNode(map[string]string{
 				"type":          "field",
-				"name":          inflection.Singular(strcase.ToLowerCamel(foreignKeyTable.GetAttrValueDefault("name", ""))),
+				"name":          fieldName,
 				"referenceType": "forward",
 				"isNonNull":     column.GetAttrValueDefault("isNonNull", "false"),
 			})
@@ -59,34 +113,7 @@ func (g *Graph) addObjectDirectFields(table *Node, object *Node) error {
 			})
 		} else {
 			// scalar field
-			valueType := "String"
-			switch strcase.ToScreamingSnake(column.GetAttrValueDefault("valueType", "")) {
-			case "INTEGER":
-				valueType = "Int"
-			case "TEXT", "BLOB":
-				valueType = "String"
-			case "REAL", "NUMERIC":
-				valueType = "Float"
-			}
-			if column.GetAttrValueDefault("isNonNull", "false") == "true" {
-				valueType += "!"
-			}
-			if column.GetAttrValueDefault("isPrimaryKey", "false") == "true" {
-				valueType = "ID!"
-			}
-
-			field := g.addNode(map[string]string{
-				"type":      "field",
-				"name":      strcase.ToLowerCamel(column.GetAttrValueDefault("name", "")),
-				"valueType": valueType,
-			})
-
-			g.addEdge(field, table, map[string]string{
-				"type": "fieldHasTable",
-			})
-			g.addEdge(field, column, map[string]string{
-				"type": "fieldHasColumn",
-			})
+			field := g.addScalarField(table, column)
 
 			g.addEdge(object, field, map[string]string{
 				"type": "objectHasField",
@@ -99,6 +126,40 @@ func (g *Graph) addObjectDirectFields(table *Node, object *Node) error {
 	return err
 }
 
+// addScalarField adds a field for a column which does not reference other objects.
+func (g *Graph) addScalarField(table *Node, column *Node) *Node {
+	valueType := "String"
+	switch strcase.ToScreamingSnake(column.GetAttrValueDefault("valueType", "")) {
+	case "INTEGER":
+		valueType = "Int"
+	case "TEXT", "BLOB":
+		valueType = "String"
+	case "REAL", "NUMERIC":
+		valueType = "Float"
+	}
+	if column.GetAttrValueDefault("isNonNull", "false") == "true" {
+		valueType += "!"
+	}
+	if column.GetAttrValueDefault("isPrimaryKey", "false") == "true" {
+		valueType = "ID!"
+	}
+
+	field := g.addNode(map[string]string{
+		"type":      "field",
+		"name":      strcase.ToLowerCamel(column.GetAttrValueDefault("name", "")),
+		"valueType": valueType,
+	})
+
+	g.addEdge(field, table, map[string]string{
+		"type": "fieldHasTable",
+	})
+	g.addEdge(field, column, map[string]string{
+		"type": "fieldHasColumn",
+	})
+
+	return field
+}
+
 func (g *Graph) addObjectBackReferenceFields() error {
 	var err error
 	g.Nodes().FilterFields().ForEach(func(field *Node) bool {
@@ -114,17 +175,35 @@ func (g *Graph) addObjectBackReferenceFields() error {
 				return false
 			}
 
-			// check that field does not exist already
-			fieldName := inflection.Plural(strcase.ToLowerCamel(field.GetAttrValueDefault("name", "") + "_" + fieldTable.GetAttrValueDefault("name", "")))
-			if g.Nodes().FilterFields().FilterName(fieldName).Len() != 0 {
-				err = errors.Errorf("field %s (attempting to create back-reference) already exists in object %+v", fieldName, referencedObject.Attrs)
-				return false
+			// name back-references after the referencing object (e.g. posts), if the referencing table has multiple
+			// references to the same table also after the forward reference (e.g. messagesBySender)
+			pluralName := strcase.ToLowerCamel(fieldObject.GetAttrValueDefault("pluralName", ""))
+			qualifiedName := pluralName + "By" + strcase.ToCamel(field.GetAttrValueDefault("name", ""))
+			candidates := []string{pluralName, qualifiedName}
+			if g.countReferences(fieldTable, referencedTable) > 1 || fieldTable == referencedTable {
+				candidates = []string{qualifiedName}
+			}
+			if fieldTable == referencedTable && field.HasAttrValue("name", "parent") {
+				// self-references to the parent are back-referenced by the children
+				candidates = []string{"children", qualifiedName}
+			}
+			// unique foreign keys are one-to-one relationships which are back-referenced by a single object
+			isOneToOne := g.IsUniqueColumn(fieldColumn)
+			if isOneToOne {
+				singularName := strcase.ToLowerCamel(fieldObject.GetAttrValueDefault("name", ""))
+				qualifiedName = singularName + "By" + strcase.ToCamel(field.GetAttrValueDefault("name", ""))
+				candidates = []string{singularName, qualifiedName}
+				if g.countReferences(fieldTable, referencedTable) > 1 || fieldTable == referencedTable {
+					candidates = []string{qualifiedName}
+				}
 			}
+			fieldName := g.uniqueFieldName(referencedObject, nil, candidates...)
 
 			field := g.addNode(map[string]string{
 				"type":          "field",
 				"name":          fieldName,
 				"referenceType": "backward",
+				"isOneToOne":    strconv.FormatBool(isOneToOne),
 			})
 
 			g.addEdge(field, fieldTable, map[string]string{
@@ -154,14 +233,66 @@ func (g *Graph) addObjectBackReferenceFields() error {
 	return err
 }
 
+func (g *Graph) addObjectRecursiveReferenceFields() error {
+	// self-referencing forward references span trees, for each of them add ancestors and descendants
+	g.Nodes().FilterFields().Filter(func(field *Node) bool {
+		return field.HasAttrValue("referenceType", "forward")
+	}).ForEach(func(field *Node) bool {
+		fieldTable := g.Edges().FilterSource(field).FilterEdgeType("fieldHasTable").Targets().First()
+		fieldColumn := g.Edges().FilterSource(field).FilterEdgeType("fieldHasColumn").Targets().First()
+		fieldObject := g.Edges().FilterTarget(field).FilterEdgeType("objectHasField").Sources().First()
+		referencedTable := g.Edges().FilterSource(field).FilterEdgeType("fieldReferencesTable").Targets().First()
+		referencedColumn := g.Edges().FilterSource(field).FilterEdgeType("fieldReferencesColumn").Targets().First()
+		if fieldTable == nil || fieldTable != referencedTable || fieldColumn == nil || referencedColumn == nil || fieldObject == nil {
+			return true
+		}
+
+		for _, direction := range []string{"ancestors", "descendants"} {
+			// tables with multiple self-references qualify the trees by the forward reference (e.g. managerAncestors)
+			fieldName := direction
+			if g.countReferences(fieldTable, fieldTable) > 1 {
+				fieldName = field.GetAttrValueDefault("name", "") + strcase.ToCamel(direction)
+			}
+
+			recursiveField := g.addNode(map[string]string{
+				"type":          "field",
+				"name":          g.uniqueFieldName(fieldObject, nil, fieldName),
+				"referenceType": "recursive",
+				"direction":     direction,
+			})
+
+			g.addEdge(recursiveField, fieldColumn, map[string]string{
+				"type": "fieldReferencesColumn",
+			})
+			g.addEdge(recursiveField, fieldObject, map[string]string{
+				"type": "fieldReferencesObject",
+			})
+			g.addEdge(recursiveField, fieldTable, map[string]string{
+				"type": "fieldHasTable",
+			})
+			g.addEdge(recursiveField, referencedColumn, map[string]string{
+				"type": "fieldHasColumn",
+			})
+
+			g.addEdge(fieldObject, recursiveField, map[string]string{
+				"type": "objectHasField",
+			})
+		}
+
+		return true
+	})
+
+	return nil
+}
+
 func (g *Graph) addObjectJoinedReferenceFields() error {
 	var err error
 	g.Nodes().FilterTables().Filter(func(n *Node) bool {
 		return n.HasAttrValue("isJoinTable", "true")
 	}).ForEach(func(table *Node) bool {
-		columns := g.Edges().FilterSource(table).FilterEdgeType("tableHasColumn").Targets().All()
+		columns := g.Edges().FilterSource(table).FilterEdgeType("tableHasColumn").Targets().FilterHasForeignKeys().All()
 		if len(columns) != 2 {
-			err = errors.Errorf("wrong amount of columns in table %+v", table.Attrs)
+			err = errors.Errorf("wrong amount of foreign key columns in table %+v", table.Attrs)
 			return false
 		}
 
@@ -192,15 +323,26 @@ func (g *Graph) addObjectJoinedReferenceFields() error {
 			return false
 		}
 
+		// name joined references after the foreign join column (e.g. tag_id becomes tags)
+		joinTableName := inflection.Singular(strcase.ToCamel(table.GetAttrValueDefault("name", "")))
+		fieldNames := map[*Node]string{}
+		for own, foreign := range map[*Node]*Node{columns[0]: columns[1], columns[1]: columns[0]} {
+			pluralName := inflection.Plural(referenceName(foreign, referencedTables[foreign]))
+			fieldNames[own] = g.uniqueFieldName(referencedObjects[own], nil, pluralName, pluralName+"By"+joinTableName)
+		}
+		if fieldNames[columns[0]] == fieldNames[columns[1]] && referencedObjects[columns[0]] == referencedObjects[columns[1]] {
+			fieldNames[columns[1]] = g.uniqueFieldName(referencedObjects[columns[1]], map[string]bool{fieldNames[columns[0]]: true}, fieldNames[columns[1]])
+		}
+
 		fields := map[*Node]*Node{
 			columns[0]: g.addNode(map[string]string{
 				"type":          "field",
-				"name":          inflection.Plural(strcase.ToLowerCamel(columns[1].GetAttrValueDefault("name", ""))),
+				"name":          fieldNames[columns[0]],
 				"referenceType": "joined",
 			}),
 			columns[1]: g.addNode(map[string]string{
 				"type":          "field",
-				"name":          inflection.Plural(strcase.ToLowerCamel(columns[0].GetAttrValueDefault("name", ""))),
+				"name":          fieldNames[columns[1]],
 				"referenceType": "joined",
 			}),
 		}
@@ -268,6 +410,22 @@ func (g *Graph) addObjectJoinedReferenceFields() error {
 			"type": "objectHasField",
 		})
 
+		// remaining columns of the join table are payload of the edges of both joined references
+		g.Edges().FilterSource(table).FilterEdgeType("tableHasColumn").Targets().Filter(func(column *Node) bool {
+			return column != columns[0] && column != columns[1]
+		}).ForEach(func(column *Node) bool {
+			edgeField := g.addScalarField(table, column)
+
+			g.addEdge(fields[columns[0]], edgeField, map[string]string{
+				"type": "fieldHasEdgeField",
+			})
+			g.addEdge(fields[columns[1]], edgeField, map[string]string{
+				"type": "fieldHasEdgeField",
+			})
+
+			return true
+		})
+
 		return true
 	})
 
@@ -282,9 +440,11 @@ func (g *Graph) AddObjects() error {
 	g.Nodes().FilterTables().Filter(func(n *Node) bool {
 		return !n.HasAttrValue("isJoinTable", "true")
 	}).ForEach(func(table *Node) bool {
+		objectName := inflection.Singular(strcase.ToCamel(table.GetAttrValueDefault("name", "")))
 		object := g.addNode(map[string]string{
-			"type": "object",
-			"name": inflection.Singular(strcase.ToCamel(table.GetAttrValueDefault("name", ""))),
+			"type":       "object",
+			"name":       objectName,
+			"pluralName": inflection.Plural(objectName),
 		})
 
 		g.addEdge(object, table, map[string]string{
@@ -306,5 +466,9 @@ func (g *Graph) AddObjects() error {
 		return err
 	}
 
+	if err := g.addObjectRecursiveReferenceFields(); err != nil {
+		return err
+	}
+
 	return g.addObjectJoinedReferenceFields()
 }
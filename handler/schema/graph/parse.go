@@ -1,6 +1,7 @@
 package graph
 
 import (
+	"dynamic-graphql-api/handler/schema/db"
 	"strings"
 
 	parse "github.com/h3ndrk/go-sqlite-createtable-parser"
@@ -117,6 +118,60 @@ func (g *Graph) addStmtTable(t *parse.Table) error {
 		}
 	}
 
+	// add unique keys from column and table constraints
+	for _, column := range t.Columns {
+		if !column.Unique {
+			continue
+		}
+
+		if err := g.addUniqueKey(table, []string{*column.Name}); err != nil {
+			return err
+		}
+	}
+	for _, constraint := range t.TableConstraints {
+		if constraint.Type != parse.TableConstraintTypeUnique {
+			continue
+		}
+
+		var columnNames []string
+		for _, indexedColumn := range constraint.IndexedColumns {
+			if indexedColumn.Name == nil {
+				return errors.New("unexpected nil unique column name")
+			}
+			columnNames = append(columnNames, *indexedColumn.Name)
+		}
+
+		if err := g.addUniqueKey(table, columnNames); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *Graph) addUniqueKey(table *Node, columnNames []string) error {
+	var columns []*Node
+	for _, columnName := range columnNames {
+		column := g.Edges().FilterSource(table).FilterEdgeType("tableHasColumn").Targets().FilterName(columnName).First()
+		if column == nil {
+			return errors.Errorf("failed to find unique column %s.%s", table.GetAttrValueDefault("name", ""), columnName)
+		}
+		columns = append(columns, column)
+	}
+
+	uniqueKey := g.addNode(map[string]string{
+		"type": "uniqueKey",
+		"name": strings.Join(columnNames, "_"),
+	})
+	g.addEdge(table, uniqueKey, map[string]string{
+		"type": "tableHasUniqueKey",
+	})
+	for _, column := range columns {
+		g.addEdge(uniqueKey, column, map[string]string{
+			"type": "uniqueKeyHasColumn",
+		})
+	}
+
 	return nil
 }
 
@@ -135,3 +190,20 @@ func (g *Graph) AddStmts(stmts []string) error {
 
 	return nil
 }
+
+// AddUniqueIndexes adds unique indexes which are not part of the statements to a graph.
+func (g *Graph) AddUniqueIndexes(uniqueIndexes []db.UniqueIndex) error {
+	for _, uniqueIndex := range uniqueIndexes {
+		table := g.Nodes().FilterTables().FilterName(uniqueIndex.Table).First()
+		if table == nil {
+			// e.g. built-in sqlite-tables
+			continue
+		}
+
+		if err := g.addUniqueKey(table, uniqueIndex.Columns); err != nil {
+			return errors.Wrap(err, "failed to add unique index")
+		}
+	}
+
+	return nil
+}
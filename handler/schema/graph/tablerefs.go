@@ -27,6 +27,29 @@ func (n Nodes) filterHaveForeignKeyAttrs() Nodes {
 	})
 }
 
+// FilterUniqueKeys filters nodes whether they are a unique key.
+func (n Nodes) FilterUniqueKeys() Nodes {
+	return n.FilterNodeType("uniqueKey")
+}
+
+// FilterUniqueColumns filters columns whose values are unique, either by being the primary key or by being the only
+// column of a unique key.
+func (n Nodes) FilterUniqueColumns() Nodes {
+	return n.Filter(n.graph.IsUniqueColumn)
+}
+
+// IsUniqueColumn returns true if the values of the column are unique, either by being the primary key or by being
+// the only column of a unique key.
+func (g *Graph) IsUniqueColumn(column *Node) bool {
+	if column.HasAttrValue("isPrimaryKey", "true") {
+		return true
+	}
+
+	return g.Edges().FilterTarget(column).FilterEdgeType("uniqueKeyHasColumn").Sources().Filter(func(uniqueKey *Node) bool {
+		return g.Edges().FilterSource(uniqueKey).FilterEdgeType("uniqueKeyHasColumn").Len() == 1
+	}).Len() > 0
+}
+
 // FilterName filters nodes whether they have a given name.
 func (n Nodes) FilterName(name string) Nodes {
 	return n.Filter(func(n *Node) bool {
@@ -100,17 +123,26 @@ func (g *Graph) AddForeignKeyReferences() error {
 	return err
 }
 
-// MarkJoinTables adds references from all foreign keys to their corresponding tables and columns.
+// MarkJoinTables marks all tables whether they are join tables. Tables already marked (e.g. by configuration) are
+// left untouched.
 func (g *Graph) MarkJoinTables() error {
 	// get all tables, for each table:
 	//   get all outgoing edges
-	//   if amount of edges != 2 -> table is not a join table
-	//   if both edges are foreign key edges -> table is a join table
-	g.Nodes().FilterTables().ForEach(func(table *Node) bool {
+	//   if amount of foreign key edges != 2 -> table is not a join table
+	//   if a column without foreign key is a primary key -> table is not a join table (it has own identity)
+	//   otherwise -> table is a join table, the remaining columns are payload of the joined edges
+	g.Nodes().FilterTables().Filter(func(n *Node) bool {
+		return !n.HasAttrKey("isJoinTable")
+	}).ForEach(func(table *Node) bool {
 		table.Attrs["isJoinTable"] = "false"
 
 		columns := g.Edges().FilterSource(table).FilterEdgeType("tableHasColumn").Targets()
-		if columns.Len() == 2 && columns.FilterHasForeignKeys().Len() == 2 {
+		foreignKeyColumns := columns.FilterHasForeignKeys()
+		isPrimaryKey := func(column *Node) bool {
+			return column.HasAttrValue("isPrimaryKey", "true")
+		}
+		hasOwnPrimaryKey := columns.Filter(isPrimaryKey).Len() > foreignKeyColumns.Filter(isPrimaryKey).Len()
+		if foreignKeyColumns.Len() == 2 && !hasOwnPrimaryKey {
 			table.Attrs["isJoinTable"] = "true"
 		}
 
@@ -1,23 +1,88 @@
 package schema
 
 import (
+	"context"
 	"dynamic-graphql-api/handler/schema/db"
 	"dynamic-graphql-api/handler/schema/graph"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/graphql-go/graphql"
 	"github.com/iancoleman/strcase"
-	"github.com/jinzhu/inflection"
 	"github.com/pkg/errors"
 )
 
 var mutation *graphql.Object
 
 type mutationPayload struct {
-	clientMutationID string
+	// clientMutationID is nil if the input did not contain a clientMutationId.
+	clientMutationID *string
 	c                cursor
 	referencedC      cursor
+	// userErrors are the constraint violations of a failed mutation, which does not return an object.
+	userErrors []UserError
+}
+
+// newMutationPayload creates the payload of a mutation of the object identified by c.
+func newMutationPayload(input map[string]interface{}, c cursor) mutationPayload {
+	payload := mutationPayload{c: c}
+	if clientMutationID, ok := input["clientMutationId"]; ok {
+		if clientMutationID, ok := clientMutationID.(string); ok {
+			payload.clientMutationID = &clientMutationID
+		}
+	}
+
+	return payload
+}
+
+// whereMutationPayload is the payload of mutations affecting the rows matching a filter.
+type whereMutationPayload struct {
+	cursors []cursor
+}
+
+func newWhereMutationPayload(objName string, ids []uint) whereMutationPayload {
+	var payload whereMutationPayload
+	for _, id := range ids {
+		payload.cursors = append(payload.cursors, cursor{object: objName, id: id})
+	}
+
+	return payload
+}
+
+func getMutationInput(p graphql.ResolveParams) (map[string]interface{}, error) {
+	inputInterface, ok := p.Args["input"]
+	if !ok {
+		return nil, errors.New("missing input")
+	}
+	input, ok := inputInterface.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("malformed input")
+	}
+
+	return input, nil
+}
+
+// getMutationInputs returns the list of inputs of bulk mutations.
+func getMutationInputs(p graphql.ResolveParams) ([]map[string]interface{}, error) {
+	inputInterface, ok := p.Args["input"]
+	if !ok {
+		return nil, errors.New("missing input")
+	}
+	inputList, ok := inputInterface.([]interface{})
+	if !ok {
+		return nil, errors.New("malformed input")
+	}
+
+	inputs := make([]map[string]interface{}, len(inputList))
+	for i, inputInterface := range inputList {
+		if inputs[i], ok = inputInterface.(map[string]interface{}); !ok {
+			return nil, errors.New("malformed input")
+		}
+	}
+
+	return inputs, nil
 }
 
 func getMutationGraphqlTypeFromField(g *graph.Graph, field *graph.Node) (graphql.Output, graphql.Output, graphql.Output, string, error) {
@@ -104,6 +169,8 @@ type mutationField struct {
 	column               string
 	isPrimaryKey         bool
 	referencedObjectName string
+	// managedTimestamp is created, updated or deleted for timestamps set by the server, which are omitted from inputs.
+	managedTimestamp string
 }
 
 func getMutationFields(g *graph.Graph, fields []*graph.Node) (map[string]mutationField, error) {
@@ -138,6 +205,11 @@ func getMutationFields(g *graph.Graph, fields []*graph.Node) (map[string]mutatio
 			column:               column.GetAttrValueDefault("name", ""),
 			isPrimaryKey:         column.GetAttrValueDefault("isPrimaryKey", "false") == "true",
 			referencedObjectName: referencedObjectName,
+			managedTimestamp:     field.GetAttrValueDefault("managedTimestamp", ""),
+		}
+		if fieldDefinition.managedTimestamp != "" {
+			mutationFields[fieldName] = fieldDefinition
+			continue
 		}
 		if fieldTypeCreate != nil {
 			fieldDefinition.fieldConfigCreate = &graphql.InputObjectFieldConfig{
@@ -163,6 +235,73 @@ func getMutationFields(g *graph.Graph, fields []*graph.Node) (map[string]mutatio
 	return mutationFields, nil
 }
 
+// newConflictEnum creates the enum of the unique keys of a table, which identify the existing row of an upsert (e.g.
+// EMAIL or USER_ID_AND_SLUG). Unique keys containing the primary key are skipped because created rows never contain
+// it. Tables without other unique keys result in nil.
+func newConflictEnum(g *graph.Graph, obj *graph.Node, table *graph.Node) (*graphql.Enum, error) {
+	objName := obj.GetAttrValueDefault("name", "")
+	fields := g.Edges().FilterSource(obj).FilterEdgeType("objectHasField").Targets().Filter(func(field *graph.Node) bool {
+		return field.HasAttrKey("valueType") || field.HasAttrValue("referenceType", "forward")
+	})
+
+	values := graphql.EnumValueConfigMap{}
+	var err error
+	g.Edges().FilterSource(table).FilterEdgeType("tableHasUniqueKey").Targets().FilterUniqueKeys().ForEach(func(uniqueKey *graph.Node) bool {
+		var (
+			columnNames  []string
+			fieldNames   []string
+			isPrimaryKey bool
+		)
+		g.Edges().FilterSource(uniqueKey).FilterEdgeType("uniqueKeyHasColumn").Targets().ForEach(func(column *graph.Node) bool {
+			if column.HasAttrValue("isPrimaryKey", "true") {
+				isPrimaryKey = true
+				return false
+			}
+
+			field := fields.Filter(func(field *graph.Node) bool {
+				return g.Edges().FilterSource(field).FilterEdgeType("fieldHasColumn").FilterTarget(column).Len() > 0
+			}).First()
+			if field == nil {
+				err = errors.Errorf("failed to find field of unique column %s.%s", table.GetAttrValueDefault("name", ""), column.GetAttrValueDefault("name", ""))
+				return false
+			}
+
+			fieldName := field.GetAttrValueDefault("name", "")
+			if field.HasAttrValue("referenceType", "forward") {
+				fieldName += "_id"
+			}
+			columnNames = append(columnNames, column.GetAttrValueDefault("name", ""))
+			fieldNames = append(fieldNames, fieldName)
+
+			return true
+		})
+		if err != nil {
+			return false
+		}
+		if isPrimaryKey {
+			return true
+		}
+
+		// enum values have to be comparable, so the columns are joined
+		values[strcase.ToScreamingSnake(strings.Join(fieldNames, "_and_"))] = &graphql.EnumValueConfig{
+			Value: strings.Join(columnNames, ","),
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	return graphql.NewEnum(graphql.EnumConfig{
+		Name:   objName + "UniqueKey",
+		Values: values,
+	}), nil
+}
+
 func addMutationAssociations(g *graph.Graph, obj *graph.Node) error {
 	objName := obj.GetAttrValueDefault("name", "")
 
@@ -200,33 +339,57 @@ func addMutationAssociations(g *graph.Graph, obj *graph.Node) error {
 
 		fmt.Printf("Found field with joined mutation: %s.%s -> %s\n", objName, fieldName, referencedObjectName)
 
-		associationName := inflection.Singular(objName) + "_" + inflection.Singular(referencedObjectName)
+		associationName := objName + "_" + referencedObjectName
 		if objName > referencedObjectName {
 			// only create mutation where objName and referenceObjectName are alphabetically ordered
 			// so that we can use the names later
 			return true
 		}
 
-		input := graphql.NewInputObject(graphql.InputObjectConfig{
-			Name: strcase.ToCamel("association_" + associationName + "_input"),
-			Fields: graphql.InputObjectConfigFieldMap{
-				"clientMutationId": &graphql.InputObjectFieldConfig{
-					Type: graphql.NewNonNull(graphql.String),
-				},
-				strcase.ToLowerCamel(objName + "_id"): &graphql.InputObjectFieldConfig{
-					Type: graphql.NewNonNull(graphql.ID),
-				},
-				strcase.ToLowerCamel(referencedObjectName + "_id"): &graphql.InputObjectFieldConfig{
-					Type: graphql.NewNonNull(graphql.ID),
-				},
+		inputFields := graphql.InputObjectConfigFieldMap{
+			"clientMutationId": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
 			},
+			strcase.ToLowerCamel(objName + "_id"): &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.ID),
+			},
+			strcase.ToLowerCamel(referencedObjectName + "_id"): &graphql.InputObjectFieldConfig{
+				Type: graphql.NewNonNull(graphql.ID),
+			},
+		}
+
+		input := graphql.NewInputObject(graphql.InputObjectConfig{
+			Name:   strcase.ToCamel("association_" + associationName + "_input"),
+			Fields: inputFields,
 		})
 
+		// columns of the join table besides the foreign keys are accepted when associating
+		edgeFields, errTemp := getMutationFields(g, g.Edges().FilterSource(field).FilterEdgeType("fieldHasEdgeField").Targets().All())
+		if errTemp != nil {
+			err = errTemp
+			return false
+		}
+		associateInput := input
+		if len(edgeFields) > 0 {
+			associateInputFields := graphql.InputObjectConfigFieldMap{}
+			for name, inputField := range inputFields {
+				associateInputFields[name] = inputField
+			}
+			for name, fieldDefinition := range edgeFields {
+				associateInputFields[name] = fieldDefinition.fieldConfigCreate
+			}
+
+			associateInput = graphql.NewInputObject(graphql.InputObjectConfig{
+				Name:   strcase.ToCamel("association_" + associationName + "_with_edge_input"),
+				Fields: associateInputFields,
+			})
+		}
+
 		payload := graphql.NewObject(graphql.ObjectConfig{
 			Name: strcase.ToCamel("association_" + associationName + "_payload"),
 			Fields: graphql.Fields{
 				"clientMutationId": &graphql.Field{
-					Type: graphql.NewNonNull(graphql.String),
+					Type: graphql.String,
 					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 						payload, ok := p.Source.(mutationPayload)
 						if !ok {
@@ -265,17 +428,13 @@ func addMutationAssociations(g *graph.Graph, obj *graph.Node) error {
 			Type: graphql.NewNonNull(payload),
 			Args: graphql.FieldConfigArgument{
 				"input": &graphql.ArgumentConfig{
-					Type: graphql.NewNonNull(input),
+					Type: graphql.NewNonNull(associateInput),
 				},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				inputInterface, ok := p.Args["input"]
-				if !ok {
-					return nil, errors.New("missing input")
-				}
-				input, ok := inputInterface.(map[string]interface{})
-				if !ok {
-					return nil, errors.New("malformed input")
+				input, err := getMutationInput(p)
+				if err != nil {
+					return nil, err
 				}
 
 				dbFromContext, err := getDBFromContext(p.Context)
@@ -283,6 +442,10 @@ func addMutationAssociations(g *graph.Graph, obj *graph.Node) error {
 					return nil, err
 				}
 
+				if err := checkOperation(p.Context, joinTable.GetAttrValueDefault("name", ""), "create"); err != nil {
+					return nil, err
+				}
+
 				var (
 					objID              uint
 					referencedObjectID uint
@@ -314,30 +477,41 @@ func addMutationAssociations(g *graph.Graph, obj *graph.Node) error {
 					}
 				}
 
+				columns := map[string]interface{}{
+					ownColumn.GetAttrValueDefault("name", ""):     objID,
+					foreignColumn.GetAttrValueDefault("name", ""): referencedObjectID,
+				}
+				for name, fieldDefinition := range edgeFields {
+					inputField, ok := input[name]
+					if !ok {
+						continue
+					}
+
+					if err := checkColumn(p.Context, joinTable.GetAttrValueDefault("name", ""), fieldDefinition.column, "write"); err != nil {
+						return nil, err
+					}
+
+					columns[fieldDefinition.column] = inputField
+				}
+
 				err = db.MutationAssociateQuery(db.MutationAssociateRequest{
 					Ctx: p.Context,
 					DB:  dbFromContext,
 
-					Table: joinTable.GetAttrValueDefault("name", ""),
-					ColumnValues: map[string]interface{}{
-						ownColumn.GetAttrValueDefault("name", ""):     objID,
-						foreignColumn.GetAttrValueDefault("name", ""): referencedObjectID,
+					Table:        joinTable.GetAttrValueDefault("name", ""),
+					ColumnValues: columns,
+					Policies: map[string]*db.Policy{
+						ownColumn.GetAttrValueDefault("name", ""):     getPolicy(p.Context, nodeTables[objName], "read"),
+						foreignColumn.GetAttrValueDefault("name", ""): getPolicy(p.Context, nodeTables[referencedObjectName], "read"),
 					},
 				})
 				if err != nil {
-					return nil, err
+					return nil, inputError(err, "input")
 				}
 
-				var payload mutationPayload
-				payload.c = cursor{object: objName, id: objID}
+				payload := newMutationPayload(input, cursor{object: objName, id: objID})
 				payload.referencedC = cursor{object: referencedObjectName, id: referencedObjectID}
 
-				if clientMutationID, ok := input["clientMutationId"]; ok {
-					if clientMutationID, ok := clientMutationID.(string); ok {
-						payload.clientMutationID = clientMutationID
-					}
-				}
-
 				return payload, nil
 			},
 		})
@@ -349,13 +523,9 @@ func addMutationAssociations(g *graph.Graph, obj *graph.Node) error {
 				},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				inputInterface, ok := p.Args["input"]
-				if !ok {
-					return nil, errors.New("missing input")
-				}
-				input, ok := inputInterface.(map[string]interface{})
-				if !ok {
-					return nil, errors.New("malformed input")
+				input, err := getMutationInput(p)
+				if err != nil {
+					return nil, err
 				}
 
 				dbFromContext, err := getDBFromContext(p.Context)
@@ -363,6 +533,10 @@ func addMutationAssociations(g *graph.Graph, obj *graph.Node) error {
 					return nil, err
 				}
 
+				if err := checkOperation(p.Context, joinTable.GetAttrValueDefault("name", ""), "delete"); err != nil {
+					return nil, err
+				}
+
 				var (
 					objID              uint
 					referencedObjectID uint
@@ -403,21 +577,18 @@ func addMutationAssociations(g *graph.Graph, obj *graph.Node) error {
 						ownColumn.GetAttrValueDefault("name", ""):     objID,
 						foreignColumn.GetAttrValueDefault("name", ""): referencedObjectID,
 					},
+					Policies: map[string]*db.Policy{
+						ownColumn.GetAttrValueDefault("name", ""):     getPolicy(p.Context, nodeTables[objName], "read"),
+						foreignColumn.GetAttrValueDefault("name", ""): getPolicy(p.Context, nodeTables[referencedObjectName], "read"),
+					},
 				})
 				if err != nil {
-					return nil, err
+					return nil, inputError(err, "input")
 				}
 
-				var payload mutationPayload
-				payload.c = cursor{object: objName, id: objID}
+				payload := newMutationPayload(input, cursor{object: objName, id: objID})
 				payload.referencedC = cursor{object: referencedObjectName, id: referencedObjectID}
 
-				if clientMutationID, ok := input["clientMutationId"]; ok {
-					if clientMutationID, ok := clientMutationID.(string); ok {
-						payload.clientMutationID = clientMutationID
-					}
-				}
-
 				return payload, nil
 			},
 		})
@@ -438,20 +609,13 @@ func initMutation(g *graph.Graph) error {
 	g.Nodes().FilterObjects().ForEach(func(obj *graph.Node) bool {
 		objName := obj.GetAttrValueDefault("name", "")
 
-		inputFieldsCreate := graphql.InputObjectConfigFieldMap{}
+		c := creators[objName]
+		mutationFields := c.mutationFields
+
 		inputFieldsUpdate := graphql.InputObjectConfigFieldMap{}
 		inputFieldsDelete := graphql.InputObjectConfigFieldMap{}
 
-		mutationFields, errTemp := getMutationFields(g, g.Edges().FilterSource(obj).FilterEdgeType("objectHasField").Targets().All())
-		if err != nil {
-			err = errTemp
-			return false
-		}
-
 		for name, fieldDefinition := range mutationFields {
-			if fieldDefinition.fieldConfigCreate != nil {
-				inputFieldsCreate[name] = fieldDefinition.fieldConfigCreate
-			}
 			if fieldDefinition.fieldConfigUpdate != nil {
 				inputFieldsUpdate[name] = fieldDefinition.fieldConfigUpdate
 			}
@@ -460,20 +624,14 @@ func initMutation(g *graph.Graph) error {
 			}
 		}
 
-		inputFieldsCreate["clientMutationId"] = &graphql.InputObjectFieldConfig{
-			Type: graphql.NewNonNull(graphql.String),
-		}
 		inputFieldsUpdate["clientMutationId"] = &graphql.InputObjectFieldConfig{
-			Type: graphql.NewNonNull(graphql.String),
+			Type: graphql.String,
 		}
 		inputFieldsDelete["clientMutationId"] = &graphql.InputObjectFieldConfig{
-			Type: graphql.NewNonNull(graphql.String),
+			Type: graphql.String,
 		}
 
-		inputCreate := graphql.NewInputObject(graphql.InputObjectConfig{
-			Name:   "Create" + objName + "Input",
-			Fields: inputFieldsCreate,
-		})
+		inputCreate := c.input("")
 		inputUpdate := graphql.NewInputObject(graphql.InputObjectConfig{
 			Name:   "Update" + objName + "Input",
 			Fields: inputFieldsUpdate,
@@ -484,7 +642,7 @@ func initMutation(g *graph.Graph) error {
 		})
 
 		payloadClientMutationIDField := &graphql.Field{
-			Type: graphql.NewNonNull(graphql.String),
+			Type: graphql.String,
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 				payload, ok := p.Source.(mutationPayload)
 				if !ok {
@@ -495,12 +653,44 @@ func initMutation(g *graph.Graph) error {
 			},
 		}
 		payloadObjectField := &graphql.Field{
-			Type: graphql.NewNonNull(graphqlObjects[objName]),
+			Type:        graphqlObjects[objName],
+			Description: "The object, which is null if the mutation failed with user errors.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				payload, ok := p.Source.(mutationPayload)
+				if !ok {
+					return nil, errors.New("malformed source")
+				}
+				if payload.userErrors != nil {
+					return nil, nil
+				}
+
+				return payload.c, nil
+			},
+		}
+		payloadUserErrorsField := &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userError))),
+			Description: "The constraint violations of the input. Selecting userErrors reports violations here instead of as errors.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				payload, ok := p.Source.(mutationPayload)
+				if !ok {
+					return nil, errors.New("malformed source")
+				}
+
+				return append([]UserError{}, payload.userErrors...), nil
+			},
+		}
+
+		// the edge of the created object allows clients to append it to connections
+		payloadEdgeField := &graphql.Field{
+			Type: graphqlEdges[objName],
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 				payload, ok := p.Source.(mutationPayload)
 				if !ok {
 					return nil, errors.New("malformed source")
 				}
+				if payload.userErrors != nil {
+					return nil, nil
+				}
 
 				return payload.c, nil
 			},
@@ -509,21 +699,37 @@ func initMutation(g *graph.Graph) error {
 		payloadCreate := graphql.NewObject(graphql.ObjectConfig{
 			Name: "Create" + objName + "Payload",
 			Fields: graphql.Fields{
-				"clientMutationId":            payloadClientMutationIDField,
-				strcase.ToLowerCamel(objName): payloadObjectField,
+				"clientMutationId":                      payloadClientMutationIDField,
+				"userErrors":                            payloadUserErrorsField,
+				strcase.ToLowerCamel(objName):           payloadObjectField,
+				strcase.ToLowerCamel(objName + "_edge"): payloadEdgeField,
 			},
 		})
 		payloadUpdate := graphql.NewObject(graphql.ObjectConfig{
 			Name: "Update" + objName + "Payload",
 			Fields: graphql.Fields{
 				"clientMutationId":            payloadClientMutationIDField,
+				"userErrors":                  payloadUserErrorsField,
 				strcase.ToLowerCamel(objName): payloadObjectField,
 			},
 		})
+		// delete payloads do not contain user errors, since the deleted ID is always set. Failed deletes return a null
+		// payload and an error with the code instead.
 		payloadDelete := graphql.NewObject(graphql.ObjectConfig{
 			Name: "Delete" + objName + "Payload",
 			Fields: graphql.Fields{
 				"clientMutationId": payloadClientMutationIDField,
+				strcase.ToLowerCamel("deleted_" + objName + "_id"): &graphql.Field{
+					Type: graphql.NewNonNull(graphql.ID),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						payload, ok := p.Source.(mutationPayload)
+						if !ok {
+							return nil, errors.New("malformed source")
+						}
+
+						return payload.c.OpaqueString(), nil
+					},
+				},
 			},
 		})
 
@@ -532,8 +738,139 @@ func initMutation(g *graph.Graph) error {
 			err = errors.New("referenced table not found")
 			return false
 		}
+		tableName := referencedTable.GetAttrValueDefault("name", "")
+
+		// setColumns converts the fields of an update input to the changed values and returns the column of the
+		// primary key if it is contained.
+		setColumns := func(ctx context.Context, input map[string]interface{}) (map[string]interface{}, string, error) {
+			var columnWithPrimaryKey string
+			columns := map[string]interface{}{}
+			for name, inputField := range input {
+				if name == "clientMutationId" {
+					continue
+				}
+
+				if fieldDefinition, ok := mutationFields[name]; ok && fieldDefinition.fieldConfigUpdate != nil {
+					if err := checkColumn(ctx, tableName, fieldDefinition.column, "write"); err != nil {
+						return nil, "", err
+					}
+
+					valueType := fieldDefinition.fieldConfigUpdate.Type.Name()
+					valueTypeWithoutNonNull := strings.TrimSuffix(valueType, "!")
+					if valueTypeWithoutNonNull == "ID" {
+						inputFieldString, ok := inputField.(string)
+						if !ok {
+							return nil, "", errors.Errorf("unknown id type of field %s", name)
+						}
+						c, err := parseCursor(inputFieldString)
+						if err != nil {
+							return nil, "", err
+						}
+						if fieldDefinition.isPrimaryKey && c.object != objName {
+							return nil, "", errors.Errorf("unexpected id type %s of field %s (expected %s)", c.object, name, objName)
+						}
+						if !fieldDefinition.isPrimaryKey && c.object != fieldDefinition.referencedObjectName {
+							return nil, "", errors.Errorf("unexpected id type %s of field %s (expected %s)", c.object, name, fieldDefinition.referencedObjectName)
+						}
+
+						columns[fieldDefinition.column] = c.id
+					} else {
+						columns[fieldDefinition.column] = inputField
+					}
+					if fieldDefinition.isPrimaryKey {
+						columnWithPrimaryKey = fieldDefinition.column
+					}
+				} else {
+					return nil, "", errors.Errorf("unexpected input field %s", name)
+				}
+			}
+			if c.updatedAt != "" {
+				columns[c.updatedAt] = time.Now().UTC()
+			}
+
+			return columns, columnWithPrimaryKey, nil
+		}
+
+		// updateColumns converts an update input to the changed values of the row and returns the column of the
+		// primary key, which identifies the row.
+		updateColumns := func(ctx context.Context, input map[string]interface{}) (map[string]interface{}, string, error) {
+			// check inputs availability (required & defined)
+			columns, columnWithPrimaryKey, err := setColumns(ctx, input)
+			if err != nil {
+				return nil, "", err
+			}
+			for name, fieldDefinition := range mutationFields {
+				if fieldDefinition.fieldConfigUpdate == nil {
+					continue
+				}
+
+				if _, ok := fieldDefinition.fieldConfigUpdate.Type.(*graphql.NonNull); ok {
+					if _, ok := input[name]; !ok {
+						return nil, "", errors.Errorf("missing required input field %s", name)
+					}
+				}
+			}
+			if columnWithPrimaryKey == "" {
+				return nil, "", errors.New("missing identification field")
+			}
 
-		mutation.AddFieldConfig(inflection.Singular(strcase.ToLowerCamel("create_"+objName)), &graphql.Field{
+			return columns, columnWithPrimaryKey, nil
+		}
+
+		// deleteColumn returns the column and the value identifying the row of a delete input.
+		deleteColumn := func(input map[string]interface{}) (string, uint, error) {
+			// check inputs availability (required & defined)
+			var (
+				columnName  string
+				columnValue uint
+			)
+			for name, inputField := range input {
+				if name == "clientMutationId" {
+					continue
+				}
+
+				if fieldDefinition, ok := mutationFields[name]; ok && fieldDefinition.fieldConfigDelete != nil {
+					valueType := fieldDefinition.fieldConfigDelete.Type.Name()
+					valueTypeWithoutNonNull := strings.TrimSuffix(valueType, "!")
+					if valueTypeWithoutNonNull == "ID" && fieldDefinition.isPrimaryKey {
+						inputFieldString, ok := inputField.(string)
+						if !ok {
+							return "", 0, errors.Errorf("unknown id type of field %s", name)
+						}
+						c, err := parseCursor(inputFieldString)
+						if err != nil {
+							return "", 0, err
+						}
+						if c.object != objName {
+							return "", 0, errors.Errorf("unexpected id type %s of field %s (expected %s)", c.object, name, objName)
+						}
+
+						columnName = fieldDefinition.column
+						columnValue = c.id
+					} // else: ignore other fields
+				} else {
+					return "", 0, errors.Errorf("unexpected input field %s", name)
+				}
+			}
+			for name, fieldDefinition := range mutationFields {
+				if fieldDefinition.fieldConfigDelete == nil {
+					continue
+				}
+
+				if _, ok := fieldDefinition.fieldConfigDelete.Type.(*graphql.NonNull); ok {
+					if _, ok := input[name]; !ok {
+						return "", 0, errors.Errorf("missing required input field %s", name)
+					}
+				}
+			}
+			if columnName == "" {
+				return "", 0, errors.New("missing identification field")
+			}
+
+			return columnName, columnValue, nil
+		}
+
+		mutation.AddFieldConfig(strcase.ToLowerCamel("create_"+objName), &graphql.Field{
 			Type: graphql.NewNonNull(payloadCreate),
 			Args: graphql.FieldConfigArgument{
 				"input": &graphql.ArgumentConfig{
@@ -541,13 +878,9 @@ func initMutation(g *graph.Graph) error {
 				},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				inputInterface, ok := p.Args["input"]
-				if !ok {
-					return nil, errors.New("missing input")
-				}
-				input, ok := inputInterface.(map[string]interface{})
-				if !ok {
-					return nil, errors.New("malformed input")
+				input, err := getMutationInput(p)
+				if err != nil {
+					return nil, err
 				}
 
 				dbFromContext, err := getDBFromContext(p.Context)
@@ -555,90 +888,274 @@ func initMutation(g *graph.Graph) error {
 					return nil, err
 				}
 
-				// check inputs availability (required & defined)
-				columns := map[string]interface{}{}
-				for name, inputField := range input {
-					if name == "clientMutationId" {
-						continue
-					}
+				// nested inputs are created in the same transaction
+				var insertedID uint
+				err = db.InTransaction(p.Context, dbFromContext, func(q db.Querier) error {
+					var err error
+					insertedID, err = c.create(p.Context, q, input, nil)
+					return err
+				})
+				if err != nil {
+					return failedMutation(p, input, err)
+				}
 
-					if fieldDefinition, ok := mutationFields[name]; ok && fieldDefinition.fieldConfigCreate != nil {
-						valueType := fieldDefinition.fieldConfigCreate.Type.Name()
-						valueTypeWithoutNonNull := strings.TrimSuffix(valueType, "!")
-						if valueTypeWithoutNonNull == "ID" {
-							inputFieldString, ok := inputField.(string)
-							if !ok {
-								return nil, errors.Errorf("unknown id type of field %s", name)
-							}
-							c, err := parseCursor(inputFieldString)
-							if err != nil {
-								return nil, err
-							}
-							if fieldDefinition.isPrimaryKey && c.object != objName {
-								return nil, errors.Errorf("unexpected id type %s of field %s (expected %s)", c.object, name, objName)
-							}
-							if !fieldDefinition.isPrimaryKey && c.object != fieldDefinition.referencedObjectName {
-								return nil, errors.Errorf("unexpected id type %s of field %s (expected %s)", c.object, name, fieldDefinition.referencedObjectName)
-							}
+				return newMutationPayload(input, cursor{object: objName, id: insertedID}), nil
+			},
+		})
+		mutation.AddFieldConfig(strcase.ToLowerCamel("update_"+objName), &graphql.Field{
+			Type: graphql.NewNonNull(payloadUpdate),
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(inputUpdate),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				input, err := getMutationInput(p)
+				if err != nil {
+					return nil, err
+				}
 
-							columns[fieldDefinition.column] = c.id
-						} else {
-							columns[fieldDefinition.column] = inputField
-						}
-					} else {
-						return nil, errors.Errorf("unexpected input field %s", name)
-					}
+				dbFromContext, err := getDBFromContext(p.Context)
+				if err != nil {
+					return nil, err
 				}
-				for name, fieldDefinition := range mutationFields {
-					if fieldDefinition.fieldConfigCreate == nil {
-						continue
-					}
 
-					if _, ok := fieldDefinition.fieldConfigCreate.Type.(*graphql.NonNull); ok {
-						if _, ok := input[name]; !ok {
-							return nil, errors.Errorf("missing required input field %s", name)
-						}
-					}
+				if err := checkOperation(p.Context, tableName, "update"); err != nil {
+					return nil, err
 				}
 
-				insertedID, err := db.MutationCreateQuery(db.MutationCreateRequest{
+				columns, columnWithPrimaryKey, err := updateColumns(p.Context, input)
+				if err != nil {
+					return nil, err
+				}
+
+				err = db.MutationUpdateQuery(db.MutationUpdateRequest{
 					Ctx: p.Context,
 					DB:  dbFromContext,
 
-					Table:        referencedTable.GetAttrValueDefault("name", ""),
-					ColumnValues: columns,
+					Table:                tableName,
+					ColumnValues:         columns,
+					ColumnWithPrimaryKey: columnWithPrimaryKey,
+					Version:              c.version,
+
+					Policy: getPolicy(p.Context, tableName, "update"),
 				})
+				if err != nil {
+					return failedMutation(p, input, err)
+				}
+
+				return newMutationPayload(input, cursor{object: objName, id: columns[columnWithPrimaryKey].(uint)}), nil
+			},
+		})
+		mutation.AddFieldConfig(strcase.ToLowerCamel("delete_"+objName), &graphql.Field{
+			Type: payloadDelete,
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(inputDelete),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				input, err := getMutationInput(p)
 				if err != nil {
 					return nil, err
 				}
 
-				var payload mutationPayload
-				payload.c = cursor{object: objName, id: insertedID}
+				dbFromContext, err := getDBFromContext(p.Context)
+				if err != nil {
+					return nil, err
+				}
 
-				if clientMutationID, ok := input["clientMutationId"]; ok {
-					if clientMutationID, ok := clientMutationID.(string); ok {
-						payload.clientMutationID = clientMutationID
-					}
+				if err := checkOperation(p.Context, tableName, "delete"); err != nil {
+					return nil, err
 				}
 
-				return payload, nil
+				columnName, columnValue, err := deleteColumn(input)
+				if err != nil {
+					return nil, err
+				}
+
+				err = db.MutationDeleteQuery(db.MutationDeleteRequest{
+					Ctx: p.Context,
+					DB:  dbFromContext,
+
+					Table:       tableName,
+					ColumnName:  columnName,
+					ColumnValue: columnValue,
+					DeletedAt:   softDeletes[tableName],
+
+					Policy: getPolicy(p.Context, tableName, "delete"),
+				})
+				if err != nil {
+					return failedMutation(p, input, err)
+				}
+
+				return newMutationPayload(input, cursor{object: objName, id: columnValue}), nil
 			},
 		})
-		mutation.AddFieldConfig(inflection.Singular(strcase.ToLowerCamel("update_"+objName)), &graphql.Field{
-			Type: graphql.NewNonNull(payloadUpdate),
+
+		// soft-deleted objects can be restored
+		if deletedAt, ok := softDeletes[tableName]; ok {
+			inputRestore := graphql.NewInputObject(graphql.InputObjectConfig{
+				Name:   "Restore" + objName + "Input",
+				Fields: inputFieldsDelete,
+			})
+			payloadRestore := graphql.NewObject(graphql.ObjectConfig{
+				Name: "Restore" + objName + "Payload",
+				Fields: graphql.Fields{
+					"clientMutationId":            payloadClientMutationIDField,
+					"userErrors":                  payloadUserErrorsField,
+					strcase.ToLowerCamel(objName): payloadObjectField,
+				},
+			})
+
+			mutation.AddFieldConfig(strcase.ToLowerCamel("restore_"+objName), &graphql.Field{
+				Type: graphql.NewNonNull(payloadRestore),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(inputRestore),
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					input, err := getMutationInput(p)
+					if err != nil {
+						return nil, err
+					}
+
+					dbFromContext, err := getDBFromContext(p.Context)
+					if err != nil {
+						return nil, err
+					}
+
+					if err := checkOperation(p.Context, tableName, "restore"); err != nil {
+						return nil, err
+					}
+
+					columnName, columnValue, err := deleteColumn(input)
+					if err != nil {
+						return nil, err
+					}
+
+					err = db.MutationRestoreQuery(db.MutationRestoreRequest{
+						Ctx: p.Context,
+						DB:  dbFromContext,
+
+						Table:       tableName,
+						ColumnName:  columnName,
+						ColumnValue: columnValue,
+						DeletedAt:   deletedAt,
+
+						Policy: getPolicyIncludingDeleted(p.Context, tableName, "update"),
+					})
+					if err != nil {
+						return failedMutation(p, input, err)
+					}
+
+					return newMutationPayload(input, cursor{object: objName, id: columnValue}), nil
+				},
+			})
+		}
+
+		conflictEnum, errTemp := newConflictEnum(g, obj, referencedTable)
+		if errTemp != nil {
+			err = errTemp
+			return false
+		}
+		if conflictEnum != nil {
+			payloadUpsert := graphql.NewObject(graphql.ObjectConfig{
+				Name: "Upsert" + objName + "Payload",
+				Fields: graphql.Fields{
+					"clientMutationId":                      payloadClientMutationIDField,
+					"userErrors":                            payloadUserErrorsField,
+					strcase.ToLowerCamel(objName):           payloadObjectField,
+					strcase.ToLowerCamel(objName + "_edge"): payloadEdgeField,
+				},
+			})
+
+			mutation.AddFieldConfig(strcase.ToLowerCamel("upsert_"+objName), &graphql.Field{
+				Type: graphql.NewNonNull(payloadUpsert),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(c.upsertInput()),
+					},
+					"conflictOn": &graphql.ArgumentConfig{
+						Type:        graphql.NewNonNull(conflictEnum),
+						Description: "The unique key identifying the row to update if it exists.",
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					input, err := getMutationInput(p)
+					if err != nil {
+						return nil, err
+					}
+					conflictOn, ok := p.Args["conflictOn"].(string)
+					if !ok {
+						return nil, errors.New("malformed conflictOn")
+					}
+
+					dbFromContext, err := getDBFromContext(p.Context)
+					if err != nil {
+						return nil, err
+					}
+
+					if err := checkOperation(p.Context, tableName, "create"); err != nil {
+						return nil, err
+					}
+					if err := checkOperation(p.Context, tableName, "update"); err != nil {
+						return nil, err
+					}
+
+					// the expected version is not a column of inserted rows
+					columnsInput := input
+					var expectedVersion interface{}
+					if fieldName, ok := c.versionField(); ok {
+						expectedVersion = input[fieldName]
+						columnsInput = map[string]interface{}{}
+						for name, value := range input {
+							if name != fieldName {
+								columnsInput[name] = value
+							}
+						}
+					}
+
+					columns, err := c.columns(p.Context, columnsInput, nil)
+					if err != nil {
+						return nil, err
+					}
+
+					id, err := db.MutationUpsertQuery(db.MutationUpsertRequest{
+						Ctx: p.Context,
+						DB:  dbFromContext,
+
+						Table:           tableName,
+						ColumnValues:    columns,
+						ConflictColumns: strings.Split(conflictOn, ","),
+						InsertColumns:   c.insertColumns(),
+						Version:         c.version,
+						ExpectedVersion: expectedVersion,
+
+						Policy: getPolicy(p.Context, tableName, "update"),
+					})
+					if err != nil {
+						return failedMutation(p, input, err)
+					}
+
+					return newMutationPayload(input, cursor{object: objName, id: id}), nil
+				},
+			})
+		}
+
+		pluralName := obj.GetAttrValueDefault("pluralName", "")
+		mutation.AddFieldConfig(strcase.ToLowerCamel("create_many_"+pluralName), &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(payloadCreate))),
 			Args: graphql.FieldConfigArgument{
 				"input": &graphql.ArgumentConfig{
-					Type: graphql.NewNonNull(inputUpdate),
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(inputCreate))),
 				},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				inputInterface, ok := p.Args["input"]
-				if !ok {
-					return nil, errors.New("missing input")
-				}
-				input, ok := inputInterface.(map[string]interface{})
-				if !ok {
-					return nil, errors.New("malformed input")
+				inputs, err := getMutationInputs(p)
+				if err != nil {
+					return nil, err
 				}
 
 				dbFromContext, err := getDBFromContext(p.Context)
@@ -646,98 +1163,118 @@ func initMutation(g *graph.Graph) error {
 					return nil, err
 				}
 
-				// check inputs availability (required & defined)
-				var columnWithPrimaryKey string
-				columns := map[string]interface{}{}
-				for name, inputField := range input {
-					if name == "clientMutationId" {
-						continue
-					}
-
-					if fieldDefinition, ok := mutationFields[name]; ok && fieldDefinition.fieldConfigUpdate != nil {
-						valueType := fieldDefinition.fieldConfigUpdate.Type.Name()
-						valueTypeWithoutNonNull := strings.TrimSuffix(valueType, "!")
-						if valueTypeWithoutNonNull == "ID" {
-							inputFieldString, ok := inputField.(string)
-							if !ok {
-								return nil, errors.Errorf("unknown id type of field %s", name)
-							}
-							c, err := parseCursor(inputFieldString)
-							if err != nil {
-								return nil, err
-							}
-							if fieldDefinition.isPrimaryKey && c.object != objName {
-								return nil, errors.Errorf("unexpected id type %s of field %s (expected %s)", c.object, name, objName)
-							}
-							if !fieldDefinition.isPrimaryKey && c.object != fieldDefinition.referencedObjectName {
-								return nil, errors.Errorf("unexpected id type %s of field %s (expected %s)", c.object, name, fieldDefinition.referencedObjectName)
-							}
+				if err := checkOperation(p.Context, tableName, "create"); err != nil {
+					return nil, err
+				}
 
-							columns[fieldDefinition.column] = c.id
-						} else {
-							columns[fieldDefinition.column] = inputField
-						}
-						if fieldDefinition.isPrimaryKey {
-							columnWithPrimaryKey = name
-						}
-					} else {
-						return nil, errors.Errorf("unexpected input field %s", name)
+				rows := make([]map[string]interface{}, len(inputs))
+				for i, input := range inputs {
+					if rows[i], err = c.columns(p.Context, input, nil); err != nil {
+						return nil, errors.Wrapf(err, "input %d", i)
 					}
 				}
-				for name, fieldDefinition := range mutationFields {
-					if fieldDefinition.fieldConfigUpdate == nil {
-						continue
+
+				var insertedIDs []uint
+				err = db.InTransaction(p.Context, dbFromContext, func(q db.Querier) error {
+					var err error
+					insertedIDs, err = db.MutationCreateManyQuery(db.MutationCreateManyRequest{
+						Ctx: p.Context,
+						DB:  q,
+
+						Table: tableName,
+						Rows:  rows,
+					})
+					if err != nil {
+						return err
 					}
 
-					if _, ok := fieldDefinition.fieldConfigUpdate.Type.(*graphql.NonNull); ok {
-						if _, ok := input[name]; !ok {
-							return nil, errors.Errorf("missing required input field %s", name)
+					for i, input := range inputs {
+						if err := c.createNested(p.Context, q, input, insertedIDs[i]); err != nil {
+							if err, ok := inputError(err, strconv.Itoa(i)).(UserError); ok {
+								return err
+							}
+							return errors.Wrapf(err, "input %d", i)
 						}
 					}
+
+					return nil
+				})
+				if err != nil {
+					return nil, rowsError(err)
 				}
-				if columnWithPrimaryKey == "" {
-					return nil, errors.New("missing identification field")
+
+				payloads := make([]mutationPayload, len(inputs))
+				for i, input := range inputs {
+					payloads[i] = newMutationPayload(input, cursor{object: objName, id: insertedIDs[i]})
 				}
 
-				err = db.MutationUpdateQuery(db.MutationUpdateRequest{
+				return payloads, nil
+			},
+		})
+		mutation.AddFieldConfig(strcase.ToLowerCamel("update_many_"+pluralName), &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(payloadUpdate))),
+			Args: graphql.FieldConfigArgument{
+				"input": &graphql.ArgumentConfig{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(inputUpdate))),
+				},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				inputs, err := getMutationInputs(p)
+				if err != nil {
+					return nil, err
+				}
+
+				dbFromContext, err := getDBFromContext(p.Context)
+				if err != nil {
+					return nil, err
+				}
+
+				if err := checkOperation(p.Context, tableName, "update"); err != nil {
+					return nil, err
+				}
+
+				var columnWithPrimaryKey string
+				rows := make([]map[string]interface{}, len(inputs))
+				for i, input := range inputs {
+					if rows[i], columnWithPrimaryKey, err = updateColumns(p.Context, input); err != nil {
+						return nil, errors.Wrapf(err, "input %d", i)
+					}
+				}
+
+				err = db.MutationUpdateManyQuery(db.MutationUpdateManyRequest{
 					Ctx: p.Context,
 					DB:  dbFromContext,
 
-					Table:                referencedTable.GetAttrValueDefault("name", ""),
-					ColumnValues:         columns,
+					Table:                tableName,
+					Rows:                 rows,
 					ColumnWithPrimaryKey: columnWithPrimaryKey,
+					Version:              c.version,
+
+					Policy: getPolicy(p.Context, tableName, "update"),
 				})
 				if err != nil {
-					return nil, err
+					return nil, rowsError(err)
 				}
 
-				var payload mutationPayload
-				payload.c = cursor{object: objName, id: columns[columnWithPrimaryKey].(uint)}
-
-				if clientMutationID, ok := input["clientMutationId"]; ok {
-					if clientMutationID, ok := clientMutationID.(string); ok {
-						payload.clientMutationID = clientMutationID
-					}
+				payloads := make([]mutationPayload, len(inputs))
+				for i, input := range inputs {
+					payloads[i] = newMutationPayload(input, cursor{object: objName, id: rows[i][columnWithPrimaryKey].(uint)})
 				}
 
-				return payload, nil
+				return payloads, nil
 			},
 		})
-		mutation.AddFieldConfig(inflection.Singular(strcase.ToLowerCamel("delete_"+objName)), &graphql.Field{
-			Type: graphql.NewNonNull(payloadDelete),
+		mutation.AddFieldConfig(strcase.ToLowerCamel("delete_many_"+pluralName), &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(payloadDelete))),
 			Args: graphql.FieldConfigArgument{
 				"input": &graphql.ArgumentConfig{
-					Type: graphql.NewNonNull(inputDelete),
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(inputDelete))),
 				},
 			},
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-				inputInterface, ok := p.Args["input"]
-				if !ok {
-					return nil, errors.New("missing input")
-				}
-				input, ok := inputInterface.(map[string]interface{})
-				if !ok {
-					return nil, errors.New("malformed input")
+				inputs, err := getMutationInputs(p)
+				if err != nil {
+					return nil, err
 				}
 
 				dbFromContext, err := getDBFromContext(p.Context)
@@ -745,76 +1282,216 @@ func initMutation(g *graph.Graph) error {
 					return nil, err
 				}
 
-				// check inputs availability (required & defined)
-				var (
-					columnName  string
-					columnValue interface{}
-				)
-				for name, inputField := range input {
-					if name == "clientMutationId" {
-						continue
+				if err := checkOperation(p.Context, tableName, "delete"); err != nil {
+					return nil, err
+				}
+
+				var columnName string
+				columnValues := make([]uint, len(inputs))
+				for i, input := range inputs {
+					if columnName, columnValues[i], err = deleteColumn(input); err != nil {
+						return nil, errors.Wrapf(err, "input %d", i)
 					}
+				}
 
-					if fieldDefinition, ok := mutationFields[name]; ok && fieldDefinition.fieldConfigDelete != nil {
-						valueType := fieldDefinition.fieldConfigDelete.Type.Name()
-						valueTypeWithoutNonNull := strings.TrimSuffix(valueType, "!")
-						if valueTypeWithoutNonNull == "ID" && fieldDefinition.isPrimaryKey {
-							inputFieldString, ok := inputField.(string)
-							if !ok {
-								return nil, errors.Errorf("unknown id type of field %s", name)
-							}
-							c, err := parseCursor(inputFieldString)
-							if err != nil {
-								return nil, err
-							}
-							if c.object != objName {
-								return nil, errors.Errorf("unexpected id type %s of field %s (expected %s)", c.object, name, objName)
-							}
+				err = db.MutationDeleteManyQuery(db.MutationDeleteManyRequest{
+					Ctx: p.Context,
+					DB:  dbFromContext,
 
-							columnName = fieldDefinition.column
-							columnValue = c.id
-						} // else: ignore other fields
-					} else {
-						return nil, errors.Errorf("unexpected input field %s", name)
-					}
+					Table:        tableName,
+					ColumnName:   columnName,
+					ColumnValues: columnValues,
+					DeletedAt:    softDeletes[tableName],
+
+					Policy: getPolicy(p.Context, tableName, "delete"),
+				})
+				if err != nil {
+					return nil, rowsError(err)
 				}
-				for name, fieldDefinition := range mutationFields {
-					if fieldDefinition.fieldConfigDelete == nil {
-						continue
+
+				payloads := make([]mutationPayload, len(inputs))
+				for i, input := range inputs {
+					payloads[i] = newMutationPayload(input, cursor{object: objName, id: columnValues[i]})
+				}
+
+				return payloads, nil
+			},
+		})
+
+		filter, filterFields := newObjectFilter(objName, mutationFields)
+
+		inputFieldsSet := graphql.InputObjectConfigFieldMap{}
+		for name, fieldDefinition := range mutationFields {
+			// versions of updates by filter are not compared, but changed
+			if c.version != nil && fieldDefinition.column == c.version.Column {
+				continue
+			}
+			if fieldDefinition.fieldConfigUpdate != nil && !fieldDefinition.isPrimaryKey {
+				inputFieldsSet[name] = fieldDefinition.fieldConfigUpdate
+			}
+		}
+
+		whereArgs := graphql.FieldConfigArgument{
+			"filter": &graphql.ArgumentConfig{
+				Type:        graphql.NewNonNull(filter),
+				Description: "The filter of the affected rows, which requires all to match all rows.",
+			},
+			"all": &graphql.ArgumentConfig{
+				Type:         graphql.Boolean,
+				DefaultValue: false,
+				Description:  "Confirms affecting all rows with a filter without conditions.",
+			},
+		}
+		// whereFilter returns the filter of the affected rows, filters without conditions must be confirmed by all
+		whereFilter := func(p graphql.ResolveParams, filterInput map[string]interface{}) (db.Filter, error) {
+			rowFilter, err := getFilter(p.Context, tableName, filterFields, filterInput)
+			if err != nil {
+				return rowFilter, err
+			}
+			if all, _ := p.Args["all"].(bool); rowFilter.MatchesAll() && !all {
+				return rowFilter, errors.New("filter without conditions matches all rows, set all to confirm")
+			}
+
+			return rowFilter, nil
+		}
+		whereFields := graphql.Fields{
+			"affectedCount": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					payload, ok := p.Source.(whereMutationPayload)
+					if !ok {
+						return nil, errors.New("malformed source")
 					}
 
-					if _, ok := fieldDefinition.fieldConfigDelete.Type.(*graphql.NonNull); ok {
-						if _, ok := input[name]; !ok {
-							return nil, errors.Errorf("missing required input field %s", name)
-						}
+					return len(payload.cursors), nil
+				},
+			},
+			"ids": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					payload, ok := p.Source.(whereMutationPayload)
+					if !ok {
+						return nil, errors.New("malformed source")
+					}
+
+					ids := []string{}
+					for _, c := range payload.cursors {
+						ids = append(ids, c.OpaqueString())
+					}
+
+					return ids, nil
+				},
+			},
+		}
+
+		// tables without other columns than the primary key cannot be updated by filter
+		if len(inputFieldsSet) > 0 {
+			inputSet := graphql.NewInputObject(graphql.InputObjectConfig{
+				Name:   "Update" + strcase.ToCamel(pluralName) + "WhereSetInput",
+				Fields: inputFieldsSet,
+			})
+
+			mutation.AddFieldConfig(strcase.ToLowerCamel("update_"+pluralName+"_where"), &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewObject(graphql.ObjectConfig{
+					Name:   "Update" + strcase.ToCamel(pluralName) + "WherePayload",
+					Fields: whereFields,
+				})),
+				Args: graphql.FieldConfigArgument{
+					"filter": whereArgs["filter"],
+					"all":    whereArgs["all"],
+					"set": &graphql.ArgumentConfig{
+						Type:        graphql.NewNonNull(inputSet),
+						Description: "The new values of the affected rows.",
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					filterInput, ok := p.Args["filter"].(map[string]interface{})
+					if !ok {
+						return nil, errors.New("malformed filter")
+					}
+					setInput, ok := p.Args["set"].(map[string]interface{})
+					if !ok {
+						return nil, errors.New("malformed set")
+					}
+
+					dbFromContext, err := getDBFromContext(p.Context)
+					if err != nil {
+						return nil, err
+					}
+
+					if err := checkOperation(p.Context, tableName, "update"); err != nil {
+						return nil, err
+					}
+
+					rowFilter, err := whereFilter(p, filterInput)
+					if err != nil {
+						return nil, err
 					}
+					columns, _, err := setColumns(p.Context, setInput)
+					if err != nil {
+						return nil, err
+					}
+
+					ids, err := db.MutationUpdateWhereQuery(db.MutationUpdateWhereRequest{
+						Ctx: p.Context,
+						DB:  dbFromContext,
+
+						Table:        tableName,
+						ColumnValues: columns,
+						Filter:       rowFilter,
+						Version:      c.version,
+
+						Policy: getPolicy(p.Context, tableName, "update"),
+					})
+					if err != nil {
+						return nil, inputError(err, "set")
+					}
+
+					return newWhereMutationPayload(objName, ids), nil
+				},
+			})
+		}
+		mutation.AddFieldConfig(strcase.ToLowerCamel("delete_"+pluralName+"_where"), &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewObject(graphql.ObjectConfig{
+				Name:   "Delete" + strcase.ToCamel(pluralName) + "WherePayload",
+				Fields: whereFields,
+			})),
+			Args: whereArgs,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				filterInput, ok := p.Args["filter"].(map[string]interface{})
+				if !ok {
+					return nil, errors.New("malformed filter")
 				}
-				if columnName == "" {
-					return nil, errors.New("missing identification field")
+
+				dbFromContext, err := getDBFromContext(p.Context)
+				if err != nil {
+					return nil, err
 				}
 
-				err = db.MutationDeleteQuery(db.MutationDeleteRequest{
-					Ctx: p.Context,
-					DB:  dbFromContext,
+				if err := checkOperation(p.Context, tableName, "delete"); err != nil {
+					return nil, err
+				}
 
-					Table:       referencedTable.GetAttrValueDefault("name", ""),
-					ColumnName:  columnName,
-					ColumnValue: columnValue,
-				})
+				rowFilter, err := whereFilter(p, filterInput)
 				if err != nil {
 					return nil, err
 				}
 
-				var payload mutationPayload
-				// skipping payload.c because Delete*Payload ignores it
+				ids, err := db.MutationDeleteWhereQuery(db.MutationDeleteWhereRequest{
+					Ctx: p.Context,
+					DB:  dbFromContext,
 
-				if clientMutationID, ok := input["clientMutationId"]; ok {
-					if clientMutationID, ok := clientMutationID.(string); ok {
-						payload.clientMutationID = clientMutationID
-					}
+					Table:     tableName,
+					Filter:    rowFilter,
+					DeletedAt: softDeletes[tableName],
+
+					Policy: getPolicy(p.Context, tableName, "delete"),
+				})
+				if err != nil {
+					return nil, inputError(err)
 				}
 
-				return payload, nil
+				return newWhereMutationPayload(objName, ids), nil
 			},
 		})
 
@@ -1,11 +1,21 @@
 package schema
 
 import (
+	"dynamic-graphql-api/handler/schema/db"
+	"dynamic-graphql-api/handler/schema/graph"
+
 	"github.com/graphql-go/graphql"
+	"github.com/pkg/errors"
 )
 
 var node *graphql.Interface
 
+// maxNodeIDs is the maximum amount of IDs resolved by a request of nodes.
+const maxNodeIDs = 1000
+
+// nodeTables maps object names to their tables for resolving nodes by global IDs.
+var nodeTables = map[string]string{}
+
 func initNodeBefore() {
 	node = graphql.NewInterface(graphql.InterfaceConfig{
 		Name: "Node",
@@ -17,6 +27,75 @@ func initNodeBefore() {
 	})
 }
 
+func initNodeTables(g *graph.Graph) error {
+	nodeTables = map[string]string{}
+
+	var err error
+	g.Nodes().FilterObjects().ForEach(func(obj *graph.Node) bool {
+		referencedTable := g.Edges().FilterSource(obj).FilterEdgeType("objectHasTable").Targets().First()
+		if referencedTable == nil {
+			err = errors.New("referenced table not found")
+			return false
+		}
+
+		nodeTables[obj.GetAttrValueDefault("name", "")] = referencedTable.GetAttrValueDefault("name", "")
+
+		return true
+	})
+
+	return err
+}
+
+// resolveNodes checks the existence of the objects identified by the cursors with one query per object type. The
+// result contains the cursors in the same order, missing objects are nil.
+func resolveNodes(p graphql.ResolveParams, cursors []cursor) ([]interface{}, error) {
+	dbFromContext, err := getDBFromContext(p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[string][]uint{}
+	for _, c := range cursors {
+		if _, ok := nodeTables[c.object]; !ok {
+			return nil, errors.Errorf("unknown object type %s", c.object)
+		}
+
+		ids[c.object] = append(ids[c.object], c.id)
+	}
+
+	existing := map[string]map[uint]bool{}
+	includeDeleted := map[string]bool{}
+	for object, objectIDs := range ids {
+		includeDeleted[object], err = getIncludeDeleted(p, nodeTables[object])
+		if err != nil {
+			return nil, err
+		}
+
+		existing[object], err = db.NodesQuery(db.NodesRequest{
+			Ctx: p.Context,
+			DB:  dbFromContext,
+
+			Table: nodeTables[object],
+			IDs:   objectIDs,
+
+			Policy: getReadPolicy(p.Context, nodeTables[object], includeDeleted[object]),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	nodes := make([]interface{}, len(cursors))
+	for i, c := range cursors {
+		if existing[c.object][c.id] {
+			c.includeDeleted = includeDeleted[c.object]
+			nodes[i] = c
+		}
+	}
+
+	return nodes, nil
+}
+
 func initNodeAfter() {
 	node.ResolveType = func(p graphql.ResolveTypeParams) *graphql.Object {
 		c, ok := p.Value.(cursor)
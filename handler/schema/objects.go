@@ -1,12 +1,14 @@
 package schema
 
 import (
+	"database/sql"
 	"dynamic-graphql-api/handler/schema/db"
 	"dynamic-graphql-api/handler/schema/graph"
 	"fmt"
 	"strings"
 
 	"github.com/graphql-go/graphql"
+	"github.com/iancoleman/strcase"
 	"github.com/pkg/errors"
 )
 
@@ -14,8 +16,18 @@ var (
 	graphqlObjects     = map[string]*graphql.Object{}
 	graphqlEdges       = map[string]*graphql.Object{}
 	graphqlConnections = map[string]*graphql.Object{}
+
+	// connections of joined fields whose edges have fields from the join table
+	graphqlJoinedConnections = map[*graph.Node]*graphql.Object{}
 )
 
+// joinedEdge is the source of edges of joined connections, the owner identifies the row in the join table together
+// with the cursor.
+type joinedEdge struct {
+	c     cursor
+	owner cursor
+}
+
 func createObjects(g *graph.Graph) {
 	g.Nodes().FilterObjects().ForEach(func(obj *graph.Node) bool {
 		objName := obj.GetAttrValueDefault("name", "")
@@ -86,6 +98,159 @@ func createObjects(g *graph.Graph) {
 	})
 }
 
+func createJoinedConnections(g *graph.Graph) error {
+	var err error
+	g.Nodes().FilterObjects().ForEach(func(obj *graph.Node) bool {
+		objName := obj.GetAttrValueDefault("name", "")
+
+		g.Edges().FilterSource(obj).FilterEdgeType("objectHasField").Targets().Filter(func(field *graph.Node) bool {
+			return g.Edges().FilterSource(field).FilterEdgeType("fieldHasEdgeField").Len() > 0
+		}).ForEach(func(field *graph.Node) bool {
+			fieldName := field.GetAttrValueDefault("name", "")
+
+			referencedObject := g.Edges().FilterSource(field).FilterEdgeType("fieldReferencesObject").Targets().First()
+			ownColumn := g.Edges().FilterSource(field).FilterEdgeType("fieldReferencesOwnJoinColumn").Targets().First()
+			foreignColumn := g.Edges().FilterSource(field).FilterEdgeType("fieldReferencesForeignJoinColumn").Targets().First()
+			joinTable := g.Edges().FilterSource(field).FilterEdgeType("fieldReferencesJoinTable").Targets().First()
+			if referencedObject == nil || ownColumn == nil || foreignColumn == nil || joinTable == nil {
+				err = errors.Errorf("incomplete joined field %s.%s", objName, fieldName)
+				return false
+			}
+			referencedObjectName := referencedObject.GetAttrValueDefault("name", "")
+			typeName := strcase.ToCamel(objName + "_" + fieldName)
+
+			edgeFields := graphql.Fields{
+				"node": &graphql.Field{
+					Type:        graphql.NewNonNull(graphqlObjects[referencedObjectName]),
+					Description: "The item at the end of the edge.",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						e, ok := p.Source.(joinedEdge)
+						if !ok {
+							return nil, errors.New("malformed source")
+						}
+
+						return e.c, nil
+					},
+				},
+				"cursor": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.ID),
+					Description: "A cursor for use in pagination.",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						e, ok := p.Source.(joinedEdge)
+						if !ok {
+							return nil, errors.New("malformed source")
+						}
+
+						return e.c.OpaqueString(), nil
+					},
+				},
+			}
+
+			g.Edges().FilterSource(field).FilterEdgeType("fieldHasEdgeField").Targets().ForEach(func(edgeField *graph.Node) bool {
+				edgeFieldName := edgeField.GetAttrValueDefault("name", "")
+				if _, ok := edgeFields[edgeFieldName]; ok {
+					err = errors.Errorf("edge field %s of joined field %s.%s already exists", edgeFieldName, objName, fieldName)
+					return false
+				}
+
+				edgeFieldType, errTemp := getScalarGraphqlTypeFromField(g, edgeField)
+				if errTemp != nil {
+					err = errTemp
+					return false
+				}
+				column := g.Edges().FilterSource(edgeField).FilterEdgeType("fieldHasColumn").Targets().First()
+				if column == nil {
+					err = errors.Errorf("edge field %s of joined field %s.%s has no column", edgeFieldName, objName, fieldName)
+					return false
+				}
+
+				edgeFields[edgeFieldName] = &graphql.Field{
+					Type: edgeFieldType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						e, ok := p.Source.(joinedEdge)
+						if !ok {
+							return nil, errors.New("malformed source")
+						}
+
+						dbFromContext, err := getDBFromContext(p.Context)
+						if err != nil {
+							return nil, err
+						}
+
+						if err := checkOperation(p.Context, joinTable.GetAttrValueDefault("name", ""), "read"); err != nil {
+							return nil, err
+						}
+						if err := checkColumn(p.Context, joinTable.GetAttrValueDefault("name", ""), column.GetAttrValueDefault("name", ""), "read"); err != nil {
+							return nil, err
+						}
+
+						return scalarQuery(edgeFieldType, db.ScalarRequest{
+							Ctx: p.Context,
+							DB:  dbFromContext,
+
+							Table:  joinTable.GetAttrValueDefault("name", ""),
+							Column: column.GetAttrValueDefault("name", ""),
+
+							Keys: map[string]interface{}{
+								ownColumn.GetAttrValueDefault("name", ""):     e.owner.id,
+								foreignColumn.GetAttrValueDefault("name", ""): e.c.id,
+							},
+						})
+					},
+				}
+
+				return true
+			})
+			if err != nil {
+				return false
+			}
+
+			edge := graphql.NewObject(graphql.ObjectConfig{
+				Name:        typeName + "Edge",
+				Description: "An edge in a connection.",
+				Fields:      edgeFields,
+			})
+
+			graphqlJoinedConnections[field] = graphql.NewObject(graphql.ObjectConfig{
+				Name:        typeName + "Connection",
+				Description: "A connection to a list of items.",
+				Fields: graphql.Fields{
+					"pageInfo": &graphql.Field{
+						Type:        graphql.NewNonNull(pageInfo),
+						Description: "Information to aid in pagination.",
+						Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+							return p.Source, nil
+						},
+					},
+					"edges": &graphql.Field{
+						Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(edge))),
+						Description: "The edges to the objects.",
+						Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+							connection, ok := p.Source.(connection)
+							if !ok {
+								return nil, errors.New("malformed source")
+							}
+
+							var edges []joinedEdge
+							for _, c := range connection.edges {
+								edges = append(edges, joinedEdge{c: c, owner: connection.owner})
+							}
+
+							return edges, nil
+						},
+					},
+				},
+			})
+
+			return true
+		})
+
+		return err == nil
+	})
+
+	return err
+}
+
 func getScalarGraphqlTypeFromField(g *graph.Graph, field *graph.Node) (graphql.Output, error) {
 	if field.HasAttrKey("valueType") {
 		valueType := field.GetAttrValueDefault("valueType", "")
@@ -120,6 +285,24 @@ func getScalarGraphqlTypeFromField(g *graph.Graph, field *graph.Node) (graphql.O
 	return nil, errors.Errorf("unknown scalar type of field %+v", field.Attrs)
 }
 
+// scalarQuery queries a scalar value of the given type from the database.
+func scalarQuery(fieldType graphql.Output, r db.ScalarRequest) (interface{}, error) {
+	switch strings.TrimSuffix(fieldType.Name(), "!") {
+	case "Int":
+		return db.ScalarIntQuery(r)
+	case "Float":
+		return db.ScalarFloatQuery(r)
+	case "String":
+		return db.ScalarStringQuery(r)
+	case "Boolean":
+		return db.ScalarBooleanQuery(r)
+	case "DateTime":
+		return db.ScalarDateTimeQuery(r)
+	}
+
+	return nil, errors.Errorf("unsupported scalar type %s", fieldType.Name())
+}
+
 func getReferenceGraphqlTypeFromField(g *graph.Graph, field *graph.Node) (graphql.Output, graphql.FieldConfigArgument, error) {
 	if field.HasAttrKey("referenceType") {
 		referencedObject := g.Edges().FilterSource(field).FilterEdgeType("fieldReferencesObject").Targets().First()
@@ -127,15 +310,36 @@ func getReferenceGraphqlTypeFromField(g *graph.Graph, field *graph.Node) (graphq
 			return nil, nil, errors.Errorf("field %+v does not reference object", field.Attrs)
 		}
 		referencedObjectName := referencedObject.GetAttrValueDefault("name", "")
+		referencedTable := g.Edges().FilterSource(referencedObject).FilterEdgeType("objectHasTable").Targets().First()
+		if referencedTable == nil {
+			return nil, nil, errors.Errorf("object %s does not have a table", referencedObjectName)
+		}
+		referencedTableName := referencedTable.GetAttrValueDefault("name", "")
 
 		var graphqlType graphql.Output
 		graphqlArgs := graphql.FieldConfigArgument{}
 		switch field.GetAttrValueDefault("referenceType", "") {
 		case "forward":
 			graphqlType = graphqlObjects[referencedObjectName]
-		case "backward", "joined":
+			// referenced rows hidden by a read policy or soft deletes resolve to null despite NOT NULL foreign keys
+			if hidesRows(referencedTableName) {
+				return graphqlType, graphqlArgs, nil
+			}
+		case "backward":
+			graphqlType = graphql.NewNonNull(graphqlConnections[referencedObjectName])
+			graphqlArgs = withIncludeDeleted(connectionArgs, referencedTableName)
+			if field.HasAttrValue("isOneToOne", "true") {
+				graphqlType = graphqlObjects[referencedObjectName]
+			}
+		case "joined":
 			graphqlType = graphql.NewNonNull(graphqlConnections[referencedObjectName])
-			graphqlArgs = connectionArgs
+			if joinedConnection, ok := graphqlJoinedConnections[field]; ok {
+				graphqlType = graphql.NewNonNull(joinedConnection)
+			}
+			graphqlArgs = withIncludeDeleted(connectionArgs, referencedTableName)
+		case "recursive":
+			graphqlType = graphql.NewNonNull(graphqlConnections[referencedObjectName])
+			graphqlArgs = withIncludeDeleted(recursiveConnectionArgs, referencedTableName)
 		default:
 			return nil, nil, errors.Errorf("unsupported reference type of field %+v", field.Attrs)
 		}
@@ -164,6 +368,12 @@ func addFields(g *graph.Graph) error {
 	g.Nodes().FilterObjects().ForEach(func(obj *graph.Node) bool {
 		objName := obj.GetAttrValueDefault("name", "")
 
+		objTable := g.Edges().FilterSource(obj).FilterEdgeType("objectHasTable").Targets().First()
+		if objTable == nil {
+			err = errors.Errorf("table of object %s not found", objName)
+			return false
+		}
+
 		g.Edges().FilterSource(obj).FilterEdgeType("objectHasField").Targets().ForEach(func(field *graph.Node) bool {
 			fieldName := field.GetAttrValueDefault("name", "")
 
@@ -189,7 +399,7 @@ func addFields(g *graph.Graph) error {
 				return false
 			}
 			foreignColumn := g.Edges().FilterSource(field).FilterEdgeType("fieldReferencesColumn").Targets().First()
-			if field.GetAttrValueDefault("referenceType", "") == "backward" && foreignColumn == nil {
+			if (field.GetAttrValueDefault("referenceType", "") == "backward" || field.GetAttrValueDefault("referenceType", "") == "recursive") && foreignColumn == nil {
 				err = errors.Errorf("referenced foreign column not found while resolving field %s.%s:%s", objName, fieldName, fieldType.Name())
 				return false
 			}
@@ -234,19 +444,21 @@ func addFields(g *graph.Graph) error {
 						return nil, err
 					}
 
-					switch fieldType.Name() {
-					case "Int", "Int!":
-						return db.ScalarIntQuery(db.ScalarRequest{
-							Ctx: p.Context,
-							DB:  dbFromContext,
+					if err := checkOperation(p.Context, objTable.GetAttrValueDefault("name", ""), "read"); err != nil {
+						return nil, err
+					}
+					if referencedColumn != nil {
+						if err := checkColumn(p.Context, referencedTable.GetAttrValueDefault("name", ""), referencedColumn.GetAttrValueDefault("name", ""), "read"); err != nil {
+							return nil, err
+						}
+					}
 
-							Table:  referencedTable.GetAttrValueDefault("name", ""),
-							Column: referencedColumn.GetAttrValueDefault("name", ""),
+					if field.HasAttrKey("valueType") {
+						if fieldType.Name() == "ID" || fieldType.Name() == "ID!" {
+							return c.OpaqueString(), nil
+						}
 
-							ID: c.id,
-						})
-					case "Float", "Float!":
-						return db.ScalarFloatQuery(db.ScalarRequest{
+						return scalarQuery(fieldType, db.ScalarRequest{
 							Ctx: p.Context,
 							DB:  dbFromContext,
 
@@ -254,19 +466,13 @@ func addFields(g *graph.Graph) error {
 							Column: referencedColumn.GetAttrValueDefault("name", ""),
 
 							ID: c.id,
-						})
-					case "String", "String!":
-						return db.ScalarStringQuery(db.ScalarRequest{
-							Ctx: p.Context,
-							DB:  dbFromContext,
 
-							Table:  referencedTable.GetAttrValueDefault("name", ""),
-							Column: referencedColumn.GetAttrValueDefault("name", ""),
-
-							ID: c.id,
+							Policy: getReadPolicy(p.Context, referencedTable.GetAttrValueDefault("name", ""), c.includeDeleted),
 						})
-					case "Boolean", "Boolean!":
-						return db.ScalarBooleanQuery(db.ScalarRequest{
+					}
+
+					if field.GetAttrValueDefault("referenceType", "") == "forward" {
+						value, err := db.ScalarIntQuery(db.ScalarRequest{
 							Ctx: p.Context,
 							DB:  dbFromContext,
 
@@ -274,31 +480,56 @@ func addFields(g *graph.Graph) error {
 							Column: referencedColumn.GetAttrValueDefault("name", ""),
 
 							ID: c.id,
+
+							Policy: getReadPolicy(p.Context, referencedTable.GetAttrValueDefault("name", ""), c.includeDeleted),
 						})
-					case "ID", "ID!":
-						return c.OpaqueString(), nil
-					case "DateTime", "DateTime!":
-						return db.ScalarDateTimeQuery(db.ScalarRequest{
-							Ctx: p.Context,
-							DB:  dbFromContext,
+						if err != nil {
+							return nil, err
+						}
+						id, ok := value.(int64)
+						if !ok {
+							return nil, nil
+						}
 
-							Table:  referencedTable.GetAttrValueDefault("name", ""),
-							Column: referencedColumn.GetAttrValueDefault("name", ""),
+						// the referenced object is only returned if it is accessible
+						if referencedPolicy := getPolicy(p.Context, nodeTables[referencedObjectName], "read"); referencedPolicy != nil {
+							existing, err := db.NodesQuery(db.NodesRequest{
+								Ctx: p.Context,
+								DB:  dbFromContext,
+
+								Table: nodeTables[referencedObjectName],
+								IDs:   []uint{uint(id)},
+
+								Policy: referencedPolicy,
+							})
+							if err != nil {
+								return nil, err
+							}
+							if !existing[uint(id)] {
+								return nil, nil
+							}
+						}
 
-							ID: c.id,
-						})
+						return cursor{object: referencedObjectName, id: uint(id)}, nil
 					}
 
-					if field.GetAttrValueDefault("referenceType", "") == "forward" {
+					if field.GetAttrValueDefault("referenceType", "") == "backward" && field.HasAttrValue("isOneToOne", "true") {
 						id, err := db.ScalarIntQuery(db.ScalarRequest{
 							Ctx: p.Context,
 							DB:  dbFromContext,
 
-							Table:  referencedTable.GetAttrValueDefault("name", ""),
-							Column: referencedColumn.GetAttrValueDefault("name", ""),
+							Table:  foreignTable.GetAttrValueDefault("name", ""),
+							Column: "id",
 
-							ID: c.id,
+							Keys: map[string]interface{}{
+								foreignColumn.GetAttrValueDefault("name", ""): c.id,
+							},
+
+							Policy: getPolicy(p.Context, foreignTable.GetAttrValueDefault("name", ""), "read"),
 						})
+						if err == sql.ErrNoRows {
+							return nil, nil
+						}
 						if err != nil {
 							return nil, err
 						}
@@ -310,7 +541,11 @@ func addFields(g *graph.Graph) error {
 					}
 
 					if field.GetAttrValueDefault("referenceType", "") == "backward" {
-						before, after, first, last, err := getConnectionArgs(p, objName)
+						before, after, first, last, err := getConnectionArgs(p, referencedObjectName)
+						if err != nil {
+							return nil, err
+						}
+						includeDeleted, err := getIncludeDeleted(p, nodeTables[referencedObjectName])
 						if err != nil {
 							return nil, err
 						}
@@ -325,37 +560,28 @@ func addFields(g *graph.Graph) error {
 								ForeignReturnColumn:    "id",
 								OwnReferenceColumn:     c.id,
 							},
+							Policy: getReadPolicy(p.Context, foreignTable.GetAttrValueDefault("name", ""), includeDeleted),
 
 							Before: before,
 							After:  after,
 							First:  first,
 							Last:   last,
+
+							PageSize: pageSizes[referencedObjectName],
 						})
 						if result.Err != nil {
 							return nil, result.Err
 						}
 
-						var conn connection
-						for _, id := range result.IDs {
-							conn.edges = append(conn.edges, cursor{object: referencedObjectName, id: id})
-						}
-
-						if len(conn.edges) > 0 {
-							conn.startCursor = conn.edges[0]
-						}
-
-						if len(conn.edges) > 0 {
-							conn.endCursor = conn.edges[len(conn.edges)-1]
-						}
-
-						conn.hasPreviousPage = result.HasPreviousPage
-						conn.hasNextPage = result.HasNextPage
-
-						return conn, nil
+						return newConnection(referencedObjectName, result, includeDeleted), nil
 					}
 
 					if field.GetAttrValueDefault("referenceType", "") == "joined" {
-						before, after, first, last, err := getConnectionArgs(p, objName)
+						before, after, first, last, err := getConnectionArgs(p, referencedObjectName)
+						if err != nil {
+							return nil, err
+						}
+						includeDeleted, err := getIncludeDeleted(p, nodeTables[referencedObjectName])
 						if err != nil {
 							return nil, err
 						}
@@ -370,33 +596,65 @@ func addFields(g *graph.Graph) error {
 								OwnColumn:     joinOwnColumn.GetAttrValueDefault("name", ""),
 								OwnValue:      c.id,
 							},
+							Policy: getReadPolicy(p.Context, nodeTables[referencedObjectName], includeDeleted),
 
 							Before: before,
 							After:  after,
 							First:  first,
 							Last:   last,
+
+							PageSize: pageSizes[referencedObjectName],
 						})
 						if result.Err != nil {
 							return nil, result.Err
 						}
 
-						var conn connection
-						for _, id := range result.IDs {
-							conn.edges = append(conn.edges, cursor{object: referencedObjectName, id: id})
-						}
+						conn := newConnection(referencedObjectName, result, includeDeleted)
+						conn.owner = c
 
-						if len(conn.edges) > 0 {
-							conn.startCursor = conn.edges[0]
-						}
+						return conn, nil
+					}
 
-						if len(conn.edges) > 0 {
-							conn.endCursor = conn.edges[len(conn.edges)-1]
+					if field.GetAttrValueDefault("referenceType", "") == "recursive" {
+						before, after, first, last, err := getConnectionArgs(p, referencedObjectName)
+						if err != nil {
+							return nil, err
+						}
+						includeDeleted, err := getIncludeDeleted(p, nodeTables[referencedObjectName])
+						if err != nil {
+							return nil, err
+						}
+						depth, err := getDepthArg(p)
+						if err != nil {
+							return nil, err
 						}
 
-						conn.hasPreviousPage = result.HasPreviousPage
-						conn.hasNextPage = result.HasNextPage
+						result := db.PaginationQuery(db.PaginationRequest{
+							Ctx: p.Context,
+							DB:  dbFromContext,
 
-						return conn, nil
+							Metadata: db.PaginationRequestRecursiveMetadata{
+								Table:        referencedTable.GetAttrValueDefault("name", ""),
+								IDColumn:     referencedColumn.GetAttrValueDefault("name", ""),
+								ParentColumn: foreignColumn.GetAttrValueDefault("name", ""),
+								ID:           c.id,
+								Ancestors:    field.HasAttrValue("direction", "ancestors"),
+								Depth:        depth,
+							},
+							Policy: getReadPolicy(p.Context, referencedTable.GetAttrValueDefault("name", ""), includeDeleted),
+
+							Before: before,
+							After:  after,
+							First:  first,
+							Last:   last,
+
+							PageSize: pageSizes[referencedObjectName],
+						})
+						if result.Err != nil {
+							return nil, result.Err
+						}
+
+						return newConnection(referencedObjectName, result, includeDeleted), nil
 					}
 
 					return nil, nil
@@ -418,6 +676,9 @@ func addFields(g *graph.Graph) error {
 func initObjects(g *graph.Graph) error {
 	// create objects, edges and connections first
 	createObjects(g)
+	if err := createJoinedConnections(g); err != nil {
+		return err
+	}
 
 	// add fields last to break circular dependencies
 	return addFields(g)
@@ -0,0 +1,87 @@
+package schema
+
+import (
+	"context"
+	"dynamic-graphql-api/handler/auth"
+	"dynamic-graphql-api/handler/config"
+
+	"github.com/pkg/errors"
+)
+
+// rolesClaim is the claim containing the roles of the caller.
+var rolesClaim = "roles"
+
+// operationRoles maps table names and operations (read, create, update, delete or restore) to the allowed roles.
+var operationRoles = map[string]map[string][]string{}
+
+// columnRoles maps table and column names to the roles allowed to read and write the column.
+var columnRoles = map[string]map[string][]string{}
+
+func initPermissions(c *config.Config) error {
+	rolesClaim = "roles"
+	if c.Auth.RolesClaim != "" {
+		rolesClaim = c.Auth.RolesClaim
+	}
+
+	operationRoles = map[string]map[string][]string{}
+	columnRoles = map[string]map[string][]string{}
+	for tableName, tableConfig := range c.Tables {
+		for operation, roles := range tableConfig.Permissions {
+			switch operation {
+			case "read", "create", "update", "delete", "restore":
+			default:
+				return errors.Errorf("unsupported permission operation %s of table %s", operation, tableName)
+			}
+
+			if operationRoles[tableName] == nil {
+				operationRoles[tableName] = map[string][]string{}
+			}
+			operationRoles[tableName][operation] = roles
+		}
+
+		for columnName, columnConfig := range tableConfig.Columns {
+			if columnConfig.Roles == nil {
+				continue
+			}
+
+			if columnRoles[tableName] == nil {
+				columnRoles[tableName] = map[string][]string{}
+			}
+			columnRoles[tableName][columnName] = columnConfig.Roles
+		}
+	}
+
+	return nil
+}
+
+func hasRole(ctx context.Context, roles []string) bool {
+	for _, callerRole := range auth.FromContext(ctx).Roles(rolesClaim) {
+		for _, role := range roles {
+			if callerRole == role {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// checkOperation returns an authorization error if the caller is not allowed to perform the operation on the table.
+func checkOperation(ctx context.Context, table string, operation string) error {
+	roles, ok := operationRoles[table][operation]
+	if !ok || hasRole(ctx, roles) {
+		return nil
+	}
+
+	return auth.AuthorizationError{Operation: operation + " " + table, Roles: roles}
+}
+
+// checkColumn returns an authorization error if the caller is not allowed to read or write the column of the table.
+func checkColumn(ctx context.Context, table string, column string, operation string) error {
+	roles, ok := columnRoles[table][column]
+	if !ok || hasRole(ctx, roles) {
+		return nil
+	}
+
+	return auth.AuthorizationError{Operation: operation + " " + table + "." + column, Roles: roles}
+}
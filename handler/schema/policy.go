@@ -0,0 +1,92 @@
+package schema
+
+import (
+	"context"
+	"dynamic-graphql-api/handler/auth"
+	"dynamic-graphql-api/handler/config"
+	"dynamic-graphql-api/handler/schema/db"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// policy is a row-level authorization predicate whose claim references were replaced by placeholders.
+type policy struct {
+	predicate string
+	claims    []string
+}
+
+// policies maps table names and operations (read, update or delete) to their policies.
+var policies = map[string]map[string]policy{}
+
+var claimReference = regexp.MustCompile(`\$claims\.([A-Za-z0-9_]+(?:\.[A-Za-z0-9_]+)*)`)
+
+func initPolicies(c *config.Config) error {
+	policies = map[string]map[string]policy{}
+
+	for tableName, tableConfig := range c.Tables {
+		for operation, predicate := range tableConfig.Policies {
+			switch operation {
+			case "read", "update", "delete":
+			default:
+				return errors.Errorf("unsupported policy operation %s of table %s", operation, tableName)
+			}
+			if predicate == "" {
+				return errors.Errorf("empty %s policy of table %s", operation, tableName)
+			}
+
+			var p policy
+			p.predicate = claimReference.ReplaceAllStringFunc(predicate, func(reference string) string {
+				p.claims = append(p.claims, claimReference.FindStringSubmatch(reference)[1])
+				return "?"
+			})
+
+			if policies[tableName] == nil {
+				policies[tableName] = map[string]policy{}
+			}
+			policies[tableName][operation] = p
+		}
+	}
+
+	return nil
+}
+
+// getPolicy returns the policy of an operation on a table with the claims of the caller as arguments, or nil if the
+// table has no policy for the operation. Missing claims are NULL, which denies access to all rows for comparisons.
+// Soft-deleted rows are not accessible.
+func getPolicy(ctx context.Context, table string, operation string) *db.Policy {
+	return excludeDeleted(getPolicyIncludingDeleted(ctx, table, operation), table)
+}
+
+// hidesRows returns whether reads of a table may skip existing rows because of a read policy or soft deletes.
+func hidesRows(table string) bool {
+	_, hasReadPolicy := policies[table]["read"]
+	_, hasSoftDeletes := softDeletes[table]
+
+	return hasReadPolicy || hasSoftDeletes
+}
+
+// getPolicyIncludingDeleted returns the policy of an operation on a table like getPolicy, but including soft-deleted
+// rows.
+func getPolicyIncludingDeleted(ctx context.Context, table string, operation string) *db.Policy {
+	p, ok := policies[table][operation]
+	if !ok {
+		return nil
+	}
+
+	claims := auth.FromContext(ctx)
+	args := make([]interface{}, len(p.claims))
+	for i, name := range p.claims {
+		value, _ := claims.Value(name)
+		switch value.(type) {
+		case string, int64, float64, bool:
+			args[i] = value
+		}
+	}
+
+	return &db.Policy{
+		Table:     table,
+		Predicate: p.predicate,
+		Args:      args,
+	}
+}
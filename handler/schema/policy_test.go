@@ -0,0 +1,105 @@
+package schema
+
+import (
+	"context"
+	"dynamic-graphql-api/handler/auth"
+	"dynamic-graphql-api/handler/config"
+	"dynamic-graphql-api/handler/schema/db"
+	"reflect"
+	"testing"
+)
+
+func TestInitPolicies(t *testing.T) {
+	defer initPolicies(&config.Config{})
+
+	tests := []struct {
+		name     string
+		policies map[string]string
+		err      bool
+	}{
+		{"read", map[string]string{"read": "owner_id = $claims.sub"}, false},
+		{"all operations", map[string]string{"read": "1", "update": "1", "delete": "1"}, false},
+		{"unsupported operation", map[string]string{"create": "owner_id = $claims.sub"}, true},
+		{"empty predicate", map[string]string{"read": ""}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := initPolicies(&config.Config{Tables: map[string]config.Table{"tasks": {Policies: test.policies}}})
+			if (err != nil) != test.err {
+				t.Fatalf("unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestGetPolicy(t *testing.T) {
+	defer initPolicies(&config.Config{})
+
+	err := initPolicies(&config.Config{Tables: map[string]config.Table{
+		"tasks": {Policies: map[string]string{
+			"read":   "owner_id = $claims.sub OR team_id = $claims.app.team",
+			"delete": "owner_id = $claims.sub AND $claims.role = 'admin'",
+		}},
+		"users": {Policies: map[string]string{
+			"read": "tenant = $claims.app_metadata.tenant.id",
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := auth.Claims{
+		"sub":  "1",
+		"role": "admin",
+		"app": map[string]interface{}{
+			"team": int64(7),
+		},
+		"app_metadata": map[string]interface{}{
+			"tenant": map[string]interface{}{"id": []interface{}{"a", "b"}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		claims    auth.Claims
+		table     string
+		operation string
+		expected  *db.Policy
+	}{
+		{
+			"claims", claims, "tasks", "read",
+			&db.Policy{Table: "tasks", Predicate: "owner_id = ? OR team_id = ?", Args: []interface{}{"1", int64(7)}},
+		},
+		{
+			"repeated placeholders", claims, "tasks", "delete",
+			&db.Policy{Table: "tasks", Predicate: "owner_id = ? AND ? = 'admin'", Args: []interface{}{"1", "admin"}},
+		},
+		{
+			"missing claims", auth.Claims{"sub": "1"}, "tasks", "read",
+			&db.Policy{Table: "tasks", Predicate: "owner_id = ? OR team_id = ?", Args: []interface{}{"1", nil}},
+		},
+		{
+			"unauthenticated", nil, "tasks", "read",
+			&db.Policy{Table: "tasks", Predicate: "owner_id = ? OR team_id = ?", Args: []interface{}{nil, nil}},
+		},
+		{
+			"no policy", claims, "tasks", "update",
+			nil,
+		},
+		{
+			// claims which are no scalars are NULL
+			"list claim", claims, "users", "read",
+			&db.Policy{Table: "users", Predicate: "tenant = ?", Args: []interface{}{nil}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy := getPolicy(auth.NewContext(context.Background(), test.claims), test.table, test.operation)
+			if !reflect.DeepEqual(policy, test.expected) {
+				t.Fatalf("expected %#v, got %#v", test.expected, policy)
+			}
+		})
+	}
+}
@@ -1,12 +1,14 @@
 package schema
 
 import (
+	"database/sql"
 	"dynamic-graphql-api/handler/schema/db"
 	"dynamic-graphql-api/handler/schema/graph"
+	"fmt"
+	"strings"
 
 	"github.com/graphql-go/graphql"
 	"github.com/iancoleman/strcase"
-	"github.com/jinzhu/inflection"
 	"github.com/pkg/errors"
 )
 
@@ -23,6 +25,7 @@ func initQuery(g *graph.Graph) error {
 						Type:        graphql.NewNonNull(graphql.ID),
 						Description: "The ID of an object",
 					},
+					"includeDeleted": includeDeletedArg,
 				},
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 					cI, ok := p.Args["id"]
@@ -43,16 +46,61 @@ func initQuery(g *graph.Graph) error {
 						return nil, errors.Errorf("unexpected parsed nil cursor '%s'", cS)
 					}
 
-					return *c, nil
+					nodes, err := resolveNodes(p, []cursor{*c})
+					if err != nil {
+						return nil, err
+					}
+
+					return nodes[0], nil
+				},
+			},
+			"nodes": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(node)),
+				Args: graphql.FieldConfigArgument{
+					"ids": &graphql.ArgumentConfig{
+						Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.ID))),
+						Description: fmt.Sprintf("The IDs of objects (at most %d)", maxNodeIDs),
+					},
+					"includeDeleted": includeDeletedArg,
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					cIs, ok := p.Args["ids"].([]interface{})
+					if !ok {
+						return nil, errors.New("missing id cursors")
+					}
+					if len(cIs) > maxNodeIDs {
+						return nil, errors.Errorf("at most %d ids can be requested", maxNodeIDs)
+					}
+
+					var cursors []cursor
+					for _, cI := range cIs {
+						cS, ok := cI.(string)
+						if !ok {
+							return nil, errors.Errorf("malformed id cursor '%v'", cI)
+						}
+
+						c, err := parseCursor(cS)
+						if err != nil {
+							return nil, errors.Wrapf(err, "failed to parse cursor '%s'", cS)
+						}
+
+						cursors = append(cursors, *c)
+					}
+
+					return resolveNodes(p, cursors)
 				},
 			},
 		},
 	})
 
+	if err := initNodeTables(g); err != nil {
+		return err
+	}
+
 	var err error
 	g.Nodes().FilterObjects().ForEach(func(obj *graph.Node) bool {
 		objName := obj.GetAttrValueDefault("name", "")
-		fieldName := inflection.Plural(strcase.ToLowerCamel(objName))
+		fieldName := strcase.ToLowerCamel(obj.GetAttrValueDefault("pluralName", ""))
 
 		referencedTable := g.Edges().FilterSource(obj).FilterEdgeType("objectHasTable").Targets().First()
 		if referencedTable == nil {
@@ -62,7 +110,7 @@ func initQuery(g *graph.Graph) error {
 
 		query.AddFieldConfig(fieldName, &graphql.Field{
 			Type: graphql.NewNonNull(graphqlConnections[objName]),
-			Args: connectionArgs,
+			Args: withIncludeDeleted(connectionArgs, referencedTable.GetAttrValueDefault("name", "")),
 			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 				dbFromContext, err := getDBFromContext(p.Context)
 				if err != nil {
@@ -73,6 +121,10 @@ func initQuery(g *graph.Graph) error {
 				if err != nil {
 					return nil, err
 				}
+				includeDeleted, err := getIncludeDeleted(p, referencedTable.GetAttrValueDefault("name", ""))
+				if err != nil {
+					return nil, err
+				}
 
 				result := db.PaginationQuery(db.PaginationRequest{
 					Ctx: p.Context,
@@ -82,34 +134,165 @@ func initQuery(g *graph.Graph) error {
 						Table:  referencedTable.GetAttrValueDefault("name", ""),
 						Column: "id",
 					},
+					Policy: getReadPolicy(p.Context, referencedTable.GetAttrValueDefault("name", ""), includeDeleted),
 
 					Before: before,
 					After:  after,
 					First:  first,
 					Last:   last,
+
+					PageSize: pageSizes[objName],
 				})
 				if result.Err != nil {
 					return nil, result.Err
 				}
 
-				// TODO: abstract result -> connection conversion
-				var conn connection
-				for _, id := range result.IDs {
-					conn.edges = append(conn.edges, cursor{object: objName, id: id})
+				return newConnection(objName, result, includeDeleted), nil
+			},
+		})
+
+		err = addUniqueKeyLookups(g, obj, referencedTable)
+		return err == nil
+	})
+
+	return err
+}
+
+type uniqueKeyArg struct {
+	column               string
+	referencedObjectName string
+}
+
+// addUniqueKeyLookups adds root fields which look up a single object by the values of a unique key (e.g.
+// userByEmail).
+func addUniqueKeyLookups(g *graph.Graph, obj *graph.Node, table *graph.Node) error {
+	objName := obj.GetAttrValueDefault("name", "")
+	fields := g.Edges().FilterSource(obj).FilterEdgeType("objectHasField").Targets().Filter(func(field *graph.Node) bool {
+		return field.HasAttrKey("valueType") || field.HasAttrValue("referenceType", "forward")
+	})
+
+	var err error
+	g.Edges().FilterSource(table).FilterEdgeType("tableHasUniqueKey").Targets().FilterUniqueKeys().ForEach(func(uniqueKey *graph.Node) bool {
+		args := graphql.FieldConfigArgument{}
+		uniqueKeyArgs := map[string]uniqueKeyArg{}
+		var argNames []string
+
+		g.Edges().FilterSource(uniqueKey).FilterEdgeType("uniqueKeyHasColumn").Targets().ForEach(func(column *graph.Node) bool {
+			field := fields.Filter(func(field *graph.Node) bool {
+				return g.Edges().FilterSource(field).FilterEdgeType("fieldHasColumn").FilterTarget(column).Len() > 0
+			}).First()
+			if field == nil {
+				err = errors.Errorf("failed to find field of unique column %s.%s", table.GetAttrValueDefault("name", ""), column.GetAttrValueDefault("name", ""))
+				return false
+			}
+
+			argName := field.GetAttrValueDefault("name", "")
+			var argType graphql.Input
+			var referencedObjectName string
+			if field.HasAttrValue("referenceType", "forward") {
+				// forward references are looked up by the ID of the referenced object
+				referencedObject := g.Edges().FilterSource(field).FilterEdgeType("fieldReferencesObject").Targets().First()
+				if referencedObject == nil {
+					err = errors.Errorf("field %+v does not reference object", field.Attrs)
+					return false
+				}
+				referencedObjectName = referencedObject.GetAttrValueDefault("name", "")
+				argName = strcase.ToLowerCamel(argName + "_id")
+				argType = graphql.ID
+			} else if column.HasAttrValue("isPrimaryKey", "true") {
+				// primary keys are looked up by the ID of the object itself
+				referencedObjectName = objName
+				argType = graphql.ID
+			} else {
+				fieldType, errTemp := getScalarGraphqlTypeFromField(g, field)
+				if errTemp != nil {
+					err = errTemp
+					return false
+				}
+				argType = graphql.GetNullable(fieldType).(graphql.Input)
+			}
+
+			args[argName] = &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(argType),
+			}
+			uniqueKeyArgs[argName] = uniqueKeyArg{
+				column:               column.GetAttrValueDefault("name", ""),
+				referencedObjectName: referencedObjectName,
+			}
+			argNames = append(argNames, strcase.ToCamel(argName))
+
+			return true
+		})
+		if err != nil {
+			return false
+		}
+
+		query.AddFieldConfig(strcase.ToLowerCamel(objName)+"By"+strings.Join(argNames, "And"), &graphql.Field{
+			Type: graphqlObjects[objName],
+			Args: withIncludeDeleted(args, table.GetAttrValueDefault("name", "")),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				dbFromContext, err := getDBFromContext(p.Context)
+				if err != nil {
+					return nil, err
+				}
+
+				if err := checkOperation(p.Context, table.GetAttrValueDefault("name", ""), "read"); err != nil {
+					return nil, err
 				}
 
-				if len(conn.edges) > 0 {
-					conn.startCursor = conn.edges[0]
+				keys := map[string]interface{}{}
+				for argName, arg := range uniqueKeyArgs {
+					value, ok := p.Args[argName]
+					if !ok {
+						return nil, errors.Errorf("missing argument %s", argName)
+					}
+					// the existence of rows must not reveal columns the caller cannot read
+					if err := checkColumn(p.Context, table.GetAttrValueDefault("name", ""), arg.column, "read"); err != nil {
+						return nil, err
+					}
+
+					if arg.referencedObjectName != "" {
+						c, err := parseCursor(fmt.Sprintf("%v", value))
+						if err != nil {
+							return nil, err
+						}
+						if c.object != arg.referencedObjectName {
+							return nil, errors.Errorf("unexpected id type %s of argument %s (expected %s)", c.object, argName, arg.referencedObjectName)
+						}
+
+						value = c.id
+					}
+
+					keys[arg.column] = value
 				}
 
-				if len(conn.edges) > 0 {
-					conn.endCursor = conn.edges[len(conn.edges)-1]
+				includeDeleted, err := getIncludeDeleted(p, table.GetAttrValueDefault("name", ""))
+				if err != nil {
+					return nil, err
 				}
 
-				conn.hasPreviousPage = result.HasPreviousPage
-				conn.hasNextPage = result.HasNextPage
+				id, err := db.ScalarIntQuery(db.ScalarRequest{
+					Ctx: p.Context,
+					DB:  dbFromContext,
+
+					Table:  table.GetAttrValueDefault("name", ""),
+					Column: "id",
+
+					Keys: keys,
+
+					Policy: getReadPolicy(p.Context, table.GetAttrValueDefault("name", ""), includeDeleted),
+				})
+				if err == sql.ErrNoRows {
+					return nil, nil
+				}
+				if err != nil {
+					return nil, err
+				}
+				if id, ok := id.(int64); ok {
+					return cursor{object: objName, id: uint(id), includeDeleted: includeDeleted}, nil
+				}
 
-				return conn, nil
+				return nil, nil
 			},
 		})
 
@@ -3,6 +3,8 @@ package schema
 import (
 	"context"
 	"database/sql"
+	"dynamic-graphql-api/handler/config"
+	"dynamic-graphql-api/handler/schema/db"
 	"dynamic-graphql-api/handler/schema/graph"
 
 	"github.com/graphql-go/graphql"
@@ -14,9 +16,18 @@ type key int
 const (
 	// KeyDB is the context key for the database value.
 	KeyDB key = iota
+	// KeyTx is the context key for the transaction of a mutation operation, which replaces the database.
+	KeyTx
+	// KeyUserErrors is the context key for a *bool, which is set if a mutation reported user errors. The transaction
+	// of the mutation operation is rolled back in this case.
+	KeyUserErrors
 )
 
-func getDBFromContext(ctx context.Context) (*sql.DB, error) {
+func getDBFromContext(ctx context.Context) (db.Querier, error) {
+	if tx, ok := ctx.Value(KeyTx).(*sql.Tx); ok {
+		return tx, nil
+	}
+
 	db, ok := ctx.Value(KeyDB).(*sql.DB)
 	if !ok {
 		return nil, errors.New("Missing DB in context")
@@ -25,13 +36,32 @@ func getDBFromContext(ctx context.Context) (*sql.DB, error) {
 	return db, nil
 }
 
-// NewSchema creates a new schema based on SQL statements.
-func NewSchema(sqls []string) (*graphql.Schema, error) {
-	objectGraph, err := graph.NewGraph(sqls)
+// NewSchema creates a new schema based on SQL statements, unique indexes and the configuration.
+func NewSchema(sqls []string, uniqueIndexes []db.UniqueIndex, c *config.Config) (*graphql.Schema, error) {
+	objectGraph, err := graph.NewGraph(sqls, uniqueIndexes, c)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := initCursor(c.IDs); err != nil {
+		return nil, err
+	}
+	if err := initPolicies(c); err != nil {
+		return nil, err
+	}
+	if err := initPermissions(c); err != nil {
+		return nil, err
+	}
+	initVersions(c)
+
+	if err := initPageSizes(objectGraph, c); err != nil {
+		return nil, err
+	}
+
+	if err := initSoftDeletes(objectGraph); err != nil {
+		return nil, err
+	}
+
 	initNodeBefore()
 	initPageInfo()
 	if err := initObjects(objectGraph); err != nil {
@@ -40,6 +70,9 @@ func NewSchema(sqls []string) (*graphql.Schema, error) {
 	if err := initQuery(objectGraph); err != nil {
 		return nil, err
 	}
+	if err := initCreators(objectGraph); err != nil {
+		return nil, err
+	}
 	if err := initMutation(objectGraph); err != nil {
 		return nil, err
 	}
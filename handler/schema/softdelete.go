@@ -0,0 +1,109 @@
+package schema
+
+import (
+	"context"
+	"dynamic-graphql-api/handler/schema/db"
+	"dynamic-graphql-api/handler/schema/graph"
+
+	"github.com/graphql-go/graphql"
+	"github.com/pkg/errors"
+)
+
+// softDeletes maps the names of tables with soft deletes to their deleted_at columns.
+var softDeletes = map[string]string{}
+
+func initSoftDeletes(g *graph.Graph) error {
+	softDeletes = map[string]string{}
+
+	var err error
+	g.Nodes().FilterObjects().ForEach(func(obj *graph.Node) bool {
+		table := g.Edges().FilterSource(obj).FilterEdgeType("objectHasTable").Targets().First()
+		if table == nil {
+			err = errors.New("referenced table not found")
+			return false
+		}
+
+		g.Edges().FilterSource(obj).FilterEdgeType("objectHasField").Targets().ForEach(func(field *graph.Node) bool {
+			if !field.HasAttrValue("managedTimestamp", "deleted") {
+				return true
+			}
+
+			column := g.Edges().FilterSource(field).FilterEdgeType("fieldHasColumn").Targets().First()
+			if column == nil {
+				err = errors.New("failed to find field's column")
+				return false
+			}
+			softDeletes[table.GetAttrValueDefault("name", "")] = column.GetAttrValueDefault("name", "")
+
+			return false
+		})
+
+		return err == nil
+	})
+
+	return err
+}
+
+// excludeDeleted restricts a policy of a table with soft deletes to rows which are not deleted.
+func excludeDeleted(policy *db.Policy, table string) *db.Policy {
+	column, ok := softDeletes[table]
+	if !ok {
+		return policy
+	}
+
+	if policy == nil {
+		return &db.Policy{Table: table, Predicate: column + " IS NULL"}
+	}
+
+	return &db.Policy{
+		Table:     table,
+		Predicate: "(" + policy.Predicate + ") AND " + column + " IS NULL",
+		Args:      policy.Args,
+	}
+}
+
+// getReadPolicy returns the read policy of a table, which includes soft-deleted rows if includeDeleted is set.
+func getReadPolicy(ctx context.Context, table string, includeDeleted bool) *db.Policy {
+	if includeDeleted {
+		return getPolicyIncludingDeleted(ctx, table, "read")
+	}
+
+	return getPolicy(ctx, table, "read")
+}
+
+var includeDeletedArg = &graphql.ArgumentConfig{
+	Type:         graphql.Boolean,
+	DefaultValue: false,
+	Description:  "Whether soft-deleted objects are included, which requires the restore permission.",
+}
+
+// withIncludeDeleted adds the includeDeleted argument to the arguments of fields reading a table with soft deletes.
+func withIncludeDeleted(args graphql.FieldConfigArgument, table string) graphql.FieldConfigArgument {
+	if _, ok := softDeletes[table]; !ok {
+		return args
+	}
+
+	argsWithIncludeDeleted := graphql.FieldConfigArgument{
+		"includeDeleted": includeDeletedArg,
+	}
+	for name, arg := range args {
+		argsWithIncludeDeleted[name] = arg
+	}
+
+	return argsWithIncludeDeleted
+}
+
+// getIncludeDeleted returns the includeDeleted argument of a field reading the table and checks the permission to
+// read soft-deleted rows.
+func getIncludeDeleted(p graphql.ResolveParams, table string) (bool, error) {
+	includeDeleted, _ := p.Args["includeDeleted"].(bool)
+	if _, ok := softDeletes[table]; !ok || !includeDeleted {
+		return false, nil
+	}
+
+	if err := checkOperation(p.Context, table, "restore"); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,203 @@
+package schema
+
+import (
+	"dynamic-graphql-api/handler/schema/db"
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/pkg/errors"
+)
+
+// UserError is a constraint violation caused by the input of a mutation.
+type UserError struct {
+	// Code is the code of the violated constraint (e.g. UNIQUE_VIOLATION).
+	Code string
+	// Field is the path of the violating input field (e.g. input, posts, 0, title). It ends at the input object if the
+	// field is unknown or the constraint consists of several fields.
+	Field   []string
+	Message string
+}
+
+func (e UserError) Error() string {
+	return e.Message
+}
+
+// Extensions adds the machine readable code and the path of the input field to the GraphQL error.
+func (e UserError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":  e.Code,
+		"field": e.Field,
+	}
+}
+
+var userError = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "UserError",
+	Description: "A constraint violation caused by the input of a mutation.",
+	Fields: graphql.Fields{
+		"code": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "The code of the violated constraint (e.g. UNIQUE_VIOLATION).",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				e, ok := p.Source.(UserError)
+				if !ok {
+					return nil, errors.New("malformed source")
+				}
+
+				return e.Code, nil
+			},
+		},
+		"field": &graphql.Field{
+			Type:        graphql.NewList(graphql.NewNonNull(graphql.String)),
+			Description: "The path of the violating input field.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				e, ok := p.Source.(UserError)
+				if !ok {
+					return nil, errors.New("malformed source")
+				}
+
+				return e.Field, nil
+			},
+		},
+		"message": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				e, ok := p.Source.(UserError)
+				if !ok {
+					return nil, errors.New("malformed source")
+				}
+
+				return e.Message, nil
+			},
+		},
+	},
+})
+
+// inputError converts constraint errors of the database to user errors of the input at path and prepends path to
+// the fields of user errors of nested inputs. Other errors are returned as they are.
+func inputError(err error, path ...string) error {
+	switch e := err.(type) {
+	case UserError:
+		e.Field = append(append([]string{}, path...), e.Field...)
+		return e
+	case db.ConstraintError:
+		return newUserError(e, path)
+	case db.ConflictError:
+		objName, names := fieldNames(e.Table, []string{e.Column})
+		return UserError{
+			Code:    "CONFLICT",
+			Field:   append(append([]string{}, path...), names...),
+			Message: fmt.Sprintf("%s was changed concurrently", objName),
+		}
+	}
+
+	return err
+}
+
+// rowsError converts constraint errors of statements writing several rows to user errors of the input list, since
+// the violating row is unknown.
+func rowsError(err error) error {
+	switch err.(type) {
+	case db.ConstraintError, db.ConflictError:
+		userErr := inputError(err).(UserError)
+		userErr.Field = []string{"input"}
+		return userErr
+	}
+
+	return inputError(err, "input")
+}
+
+// fieldNames returns the object of the table and the fields of its columns. Join tables do not have an object and
+// keep their names.
+func fieldNames(table string, columns []string) (string, []string) {
+	for _, c := range creators {
+		if c.table != table {
+			continue
+		}
+
+		var names []string
+		for _, column := range columns {
+			name := column
+			for fieldName, fieldDefinition := range c.mutationFields {
+				if fieldDefinition.column == column {
+					name = fieldName
+				}
+			}
+			names = append(names, name)
+		}
+
+		return c.objName, names
+	}
+
+	return table, columns
+}
+
+func newUserError(e db.ConstraintError, path []string) UserError {
+	objName, names := fieldNames(e.Table, e.Columns)
+
+	field := append([]string{}, path...)
+	if len(names) == 1 {
+		field = append(field, names[0])
+	}
+
+	var message string
+	switch e.Code {
+	case "UNIQUE_VIOLATION":
+		message = fmt.Sprintf("%s with the same %s already exists", objName, strings.Join(names, " and "))
+	case "NOT_NULL_VIOLATION":
+		message = fmt.Sprintf("%s must not be null", strings.Join(names, " and "))
+	case "FOREIGN_KEY_VIOLATION":
+		message = "referenced object does not exist or object is still referenced"
+	case "CHECK_VIOLATION":
+		message = fmt.Sprintf("check constraint %s failed", e.Constraint)
+	}
+
+	return UserError{Code: e.Code, Field: field, Message: message}
+}
+
+// failedMutation returns the payload with the user errors of a failed mutation if the payload selects userErrors.
+// Otherwise the error is returned as error of the mutation field.
+func failedMutation(p graphql.ResolveParams, input map[string]interface{}, err error) (interface{}, error) {
+	err = inputError(err, "input")
+	e, ok := err.(UserError)
+	if !ok || !selectsField(p.Info, p.Info.FieldASTs[0].SelectionSet, "userErrors") {
+		return nil, err
+	}
+
+	// the transaction is rolled back though the mutation field succeeded
+	if failed, ok := p.Context.Value(KeyUserErrors).(*bool); ok {
+		*failed = true
+	}
+
+	payload := newMutationPayload(input, cursor{})
+	payload.userErrors = []UserError{e}
+
+	return payload, nil
+}
+
+// selectsField returns whether the selection set or its fragments select the field.
+func selectsField(info graphql.ResolveInfo, selectionSet *ast.SelectionSet, name string) bool {
+	if selectionSet == nil {
+		return false
+	}
+
+	for _, selection := range selectionSet.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if selection.Name != nil && selection.Name.Value == name {
+				return true
+			}
+		case *ast.InlineFragment:
+			if selectsField(info, selection.SelectionSet, name) {
+				return true
+			}
+		case *ast.FragmentSpread:
+			if fragment, ok := info.Fragments[selection.Name.Value].(*ast.FragmentDefinition); ok && selectsField(info, fragment.SelectionSet, name) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
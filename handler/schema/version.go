@@ -0,0 +1,57 @@
+package schema
+
+import (
+	"dynamic-graphql-api/handler/config"
+	"dynamic-graphql-api/handler/schema/db"
+
+	"github.com/graphql-go/graphql"
+	"github.com/pkg/errors"
+)
+
+// versions maps table names to their version columns.
+var versions = map[string]string{}
+
+func initVersions(c *config.Config) {
+	versions = map[string]string{}
+
+	for tableName, tableConfig := range c.Tables {
+		if tableConfig.Version != "" {
+			versions[tableName] = tableConfig.Version
+		}
+	}
+}
+
+// applyVersion makes the version field required in updates and removes it from creates, which set the initial
+// version. It returns nil if the table has no version column.
+func applyVersion(table string, mutationFields map[string]mutationField) (*db.Version, error) {
+	column, ok := versions[table]
+	if !ok {
+		return nil, nil
+	}
+
+	for name, fieldDefinition := range mutationFields {
+		if fieldDefinition.column != column || fieldDefinition.fieldConfigUpdate == nil {
+			continue
+		}
+
+		version := &db.Version{Column: column}
+		switch valueType := fieldDefinition.fieldConfigUpdate.Type.Name(); valueType {
+		case "Int":
+			version.Increment = true
+		case "DateTime":
+		default:
+			return nil, errors.Errorf("unsupported type %s of version column %s.%s", valueType, table, column)
+		}
+
+		fieldDefinition.fieldConfigCreate = nil
+		fieldDefinition.fieldConfigUpdate = &graphql.InputObjectFieldConfig{
+			Type:        graphql.NewNonNull(fieldDefinition.fieldConfigUpdate.Type),
+			Description: "The version of the object read before, the update fails if the object was changed since.",
+		}
+		mutationFields[name] = fieldDefinition
+
+		return version, nil
+	}
+
+	return nil, errors.Errorf("version column %s.%s not found", table, column)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"dynamic-graphql-api/handler/schema"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/pkg/errors"
+)
+
+// TransactionError is reported if the transaction of a mutation operation was rolled back or failed.
+type TransactionError struct {
+	Message string
+	Code    string
+}
+
+func (e TransactionError) Error() string {
+	return e.Message
+}
+
+// Extensions adds a machine readable code to the GraphQL error.
+func (e TransactionError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code": e.Code,
+	}
+}
+
+// executeInTransaction executes a mutation operation inside a transaction, which is only committed if all root fields
+// succeeded without user errors. Errors of nested fields (e.g. reading the returned objects) do not roll back the
+// transaction.
+func (h Handler) executeInTransaction(ctx context.Context, params graphql.Params) *graphql.Result {
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{formatError(errors.Wrap(err, "failed to begin transaction"))}}
+	}
+
+	// mutations reporting user errors in their payloads succeed, but roll back the transaction
+	var userErrors bool
+	params.Context = context.WithValue(context.WithValue(ctx, schema.KeyTx, tx), schema.KeyUserErrors, &userErrors)
+	result := graphql.Do(params)
+
+	if userErrors || rootFieldFailed(result) {
+		tx.Rollback()
+		if executed(result) || userErrors {
+			result.Errors = append(result.Errors, formatError(TransactionError{
+				Message: "all mutations were rolled back because a mutation failed",
+				Code:    "TRANSACTION_ROLLED_BACK",
+			}))
+		}
+
+		return result
+	}
+
+	if err := tx.Commit(); err != nil {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{formatError(TransactionError{
+			Message: "failed to commit transaction: " + err.Error(),
+			Code:    "TRANSACTION_FAILED",
+		})}}
+	}
+
+	return result
+}
+
+// rootFieldFailed returns whether the execution or a root field of the result failed.
+func rootFieldFailed(result *graphql.Result) bool {
+	for _, err := range result.Errors {
+		if len(err.Path) <= 1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// executed returns whether the operation was executed, in contrast to errors of the parsing or validation, which do
+// not have a path.
+func executed(result *graphql.Result) bool {
+	for _, err := range result.Errors {
+		if len(err.Path) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
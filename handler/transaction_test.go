@@ -0,0 +1,92 @@
+package handler
+
+import "testing"
+
+const transactionSchema = `
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL CHECK (name <> ''));
+`
+
+func TestMutationsInTransaction(t *testing.T) {
+	tests := []struct {
+		name       string
+		request    map[string]interface{}
+		rolledBack bool
+		users      int
+	}{
+		{
+			name: "all mutations succeed",
+			request: map[string]interface{}{
+				"query": `mutation {
+					a: createUser(input: {clientMutationId: "a", name: "a"}) { clientMutationId }
+					b: createUser(input: {clientMutationId: "b", name: "b"}) { clientMutationId }
+				}`,
+			},
+			users: 2,
+		},
+		{
+			name: "a mutation fails",
+			request: map[string]interface{}{
+				"query": `mutation {
+					a: createUser(input: {clientMutationId: "a", name: "a"}) { clientMutationId }
+					b: createUser(input: {clientMutationId: "b", name: ""}) { clientMutationId }
+				}`,
+			},
+			rolledBack: true,
+			users:      0,
+		},
+		{
+			// invalid operations are not executed
+			name: "validation fails",
+			request: map[string]interface{}{
+				"query": `mutation { createUser(input: {clientMutationId: "a", unknown: "a"}) { clientMutationId } }`,
+			},
+			users: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestServer(t, transactionSchema, "")
+			defer s.Close()
+
+			response := s.post("", test.request)
+			if rolledBack := response.hasErrorCode("TRANSACTION_ROLLED_BACK"); rolledBack != test.rolledBack {
+				t.Errorf("rolled back %t, errors %v", rolledBack, response.errorMessages())
+			}
+			if users := s.count("SELECT COUNT(*) FROM users"); users != test.users {
+				t.Errorf("expected %d users, got %d", test.users, users)
+			}
+		})
+	}
+}
+
+// The root fields of mutations are executed in random order and a failed field stops the execution of the following
+// fields, so without transaction the created user only remains if it was created first.
+func TestMutationsWithoutTransaction(t *testing.T) {
+	request := map[string]interface{}{
+		"query": `mutation {
+			a: createUser(input: {clientMutationId: "a", name: "a"}) { clientMutationId }
+			b: createUser(input: {clientMutationId: "b", name: ""}) { clientMutationId }
+		}`,
+		"extensions": map[string]interface{}{"transaction": false},
+	}
+
+	var remained bool
+	for i := 0; i < 20 && !remained; i++ {
+		s := newTestServer(t, transactionSchema, "")
+		response := s.post("", request)
+		users := s.count("SELECT COUNT(*) FROM users")
+		s.Close()
+
+		if response.hasErrorCode("TRANSACTION_ROLLED_BACK") || len(response.Errors) != 1 {
+			t.Fatalf("unexpected errors %v", response.errorMessages())
+		}
+		if users > 1 {
+			t.Fatalf("created %d users", users)
+		}
+		remained = users == 1
+	}
+	if !remained {
+		t.Error("the created user never remained")
+	}
+}
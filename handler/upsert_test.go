@@ -0,0 +1,99 @@
+package handler
+
+import "testing"
+
+const upsertSchema = `
+CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL UNIQUE, name TEXT);
+CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL REFERENCES users(id), title TEXT);
+INSERT INTO users (email, name) VALUES ('a@x', 'a'), ('b@x', 'b');
+`
+
+const upsertConfig = testJWTConfig + `
+tables:
+  users:
+    policies:
+      update: id = $claims.sub
+`
+
+func TestUpsert(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  map[string]interface{}
+		email  string
+		id     int
+		failed bool
+		check  string
+		count  int
+	}{
+		{
+			name:  "insert",
+			email: "c@x",
+			id:    3,
+			check: "SELECT COUNT(*) FROM users WHERE id = 3 AND email = 'c@x' AND name = 'changed'",
+			count: 1,
+		},
+		{
+			name:  "update",
+			token: map[string]interface{}{"sub": 2},
+			email: "b@x",
+			id:    2,
+			check: "SELECT COUNT(*) FROM users WHERE (id = 2 AND name = 'changed') OR id > 2",
+			count: 1,
+		},
+		{
+			name:   "update restricted by the policy",
+			token:  map[string]interface{}{"sub": 2},
+			email:  "a@x",
+			failed: true,
+			check:  "SELECT COUNT(*) FROM users WHERE name = 'changed'",
+			count:  0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestServer(t, upsertSchema, upsertConfig)
+			defer s.Close()
+
+			var token string
+			if test.token != nil {
+				token = testToken(t, test.token)
+			}
+			response := s.do(token, `mutation {
+				upsertUser(conflictOn: EMAIL, input: {clientMutationId: "1", email: "`+test.email+`", name: "changed"}) {
+					user { id }
+				}
+			}`)
+			if failed := len(response.Errors) > 0; failed != test.failed {
+				t.Fatalf("unexpected errors %v", response.errorMessages())
+			}
+			if !test.failed {
+				id := response.Data["upsertUser"].(map[string]interface{})["user"].(map[string]interface{})["id"]
+				if expected := testID("User", test.id); id != expected {
+					t.Errorf("returned %v, expected %s", id, expected)
+				}
+			}
+			if count := s.count(test.check); count != test.count {
+				t.Errorf("%s returned %d, expected %d", test.check, count, test.count)
+			}
+		})
+	}
+}
+
+// Nested inputs would be created again for every upsert of an existing row.
+func TestUpsertRejectsNestedInputs(t *testing.T) {
+	s := newTestServer(t, upsertSchema, upsertConfig)
+	defer s.Close()
+
+	response := s.do("", `mutation {
+		upsertUser(conflictOn: EMAIL, input: {clientMutationId: "1", email: "c@x", posts: [{title: "p"}]}) {
+			user { id }
+		}
+	}`)
+	if len(response.Errors) == 0 {
+		t.Error("accepted a nested input")
+	}
+	if count := s.count("SELECT COUNT(*) FROM users WHERE email = 'c@x'") + s.count("SELECT COUNT(*) FROM posts"); count != 0 {
+		t.Errorf("created %d rows", count)
+	}
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const versionSchema = `
+CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL UNIQUE, name TEXT, version INTEGER);
+INSERT INTO users (email, name, version) VALUES ('a@x', 'a', 1), ('b@x', 'b', 3);
+`
+
+const versionConfig = `
+tables:
+  users:
+    version: version
+`
+
+func TestVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		failed   bool
+		conflict bool
+		check    string
+		count    int
+	}{
+		{
+			name:  "update",
+			query: fmt.Sprintf(`mutation { updateUser(input: {clientMutationId: "1", id: "%s", name: "x", version: 1}) { user { id } } }`, testID("User", 1)),
+			check: "SELECT COUNT(*) FROM users WHERE id = 1 AND name = 'x' AND version = 2",
+			count: 1,
+		},
+		{
+			name:     "update of a changed row",
+			query:    fmt.Sprintf(`mutation { updateUser(input: {clientMutationId: "1", id: "%s", name: "x", version: 2}) { user { id } } }`, testID("User", 2)),
+			failed:   true,
+			conflict: true,
+			check:    "SELECT COUNT(*) FROM users WHERE id = 2 AND name = 'b' AND version = 3",
+			count:    1,
+		},
+		{
+			name:   "update of a missing row",
+			query:  fmt.Sprintf(`mutation { updateUser(input: {clientMutationId: "1", id: "%s", name: "x", version: 1}) { user { id } } }`, testID("User", 9)),
+			failed: true,
+			check:  "SELECT COUNT(*) FROM users WHERE name = 'x'",
+			count:  0,
+		},
+		{
+			name: "update of many rows",
+			query: fmt.Sprintf(`mutation { updateManyUsers(input: [{clientMutationId: "1", id: "%s", name: "x", version: 1}, {clientMutationId: "2", id: "%s", name: "y", version: 3}]) { user { id } } }`,
+				testID("User", 1), testID("User", 2)),
+			check: "SELECT COUNT(*) FROM users WHERE (id = 1 AND name = 'x' AND version = 2) OR (id = 2 AND name = 'y' AND version = 4)",
+			count: 2,
+		},
+		{
+			name: "update of many rows with a changed row",
+			query: fmt.Sprintf(`mutation { updateManyUsers(input: [{clientMutationId: "1", id: "%s", name: "x", version: 1}, {clientMutationId: "2", id: "%s", name: "y", version: 2}]) { user { id } } }`,
+				testID("User", 1), testID("User", 2)),
+			failed:   true,
+			conflict: true,
+			check:    "SELECT COUNT(*) FROM users WHERE name IN ('x', 'y') OR version NOT IN (1, 3)",
+			count:    0,
+		},
+		{
+			name:  "update by filter",
+			query: `mutation { updateUsersWhere(filter: {}, all: true, set: {name: "x"}) { affectedCount } }`,
+			check: "SELECT COUNT(*) FROM users WHERE (id = 1 AND version = 2) OR (id = 2 AND version = 4)",
+			count: 2,
+		},
+		{
+			name:  "upsert of an existing row",
+			query: `mutation { upsertUser(conflictOn: EMAIL, input: {clientMutationId: "1", email: "a@x", name: "x", version: 1}) { user { id } } }`,
+			check: "SELECT COUNT(*) FROM users WHERE id = 1 AND name = 'x' AND version = 2",
+			count: 1,
+		},
+		{
+			name:     "upsert of a changed row",
+			query:    `mutation { upsertUser(conflictOn: EMAIL, input: {clientMutationId: "1", email: "b@x", name: "x", version: 2}) { user { id } } }`,
+			failed:   true,
+			conflict: true,
+			check:    "SELECT COUNT(*) FROM users WHERE id = 2 AND name = 'b' AND version = 3",
+			count:    1,
+		},
+		{
+			name:     "upsert of an existing row without version",
+			query:    `mutation { upsertUser(conflictOn: EMAIL, input: {clientMutationId: "1", email: "a@x", name: "x"}) { user { id } } }`,
+			failed:   true,
+			conflict: true,
+			check:    "SELECT COUNT(*) FROM users WHERE id = 1 AND name = 'a' AND version = 1",
+			count:    1,
+		},
+		{
+			name:  "upsert of a new row",
+			query: `mutation { upsertUser(conflictOn: EMAIL, input: {clientMutationId: "1", email: "c@x", name: "x"}) { user { id } } }`,
+			check: "SELECT COUNT(*) FROM users WHERE email = 'c@x' AND version = 1",
+			count: 1,
+		},
+		{
+			name:  "create",
+			query: `mutation { createUser(input: {clientMutationId: "1", email: "c@x"}) { user { id } } }`,
+			check: "SELECT COUNT(*) FROM users WHERE email = 'c@x' AND version = 1",
+			count: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestServer(t, versionSchema, versionConfig)
+			defer s.Close()
+
+			response := s.do("", test.query)
+			if failed := len(response.Errors) > 0; failed != test.failed {
+				t.Errorf("unexpected errors %v", response.errorMessages())
+			}
+			if conflict := response.hasErrorCode("CONFLICT"); conflict != test.conflict {
+				t.Errorf("unexpected conflict %v", response.errorMessages())
+			}
+			if count := s.count(test.check); count != test.count {
+				t.Errorf("%s returned %d, expected %d", test.check, count, test.count)
+			}
+		})
+	}
+}
+
+// Statements of many rows also contain the new date-time version, which counts toward the limit of variables.
+func TestUpdateManyDateTimeVersions(t *testing.T) {
+	s := newTestServer(t, `
+		CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT, updated_at DATETIME);
+		WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 250)
+		INSERT INTO items (name, updated_at) SELECT 'old', '2020-01-01 00:00:00' FROM n;
+	`, `
+tables:
+  items:
+    version: updated_at
+    columns:
+      updated_at: {type: DateTime}
+`)
+	defer s.Close()
+
+	// rows with a name take 5 variables and rows without 3, so the first statement takes exactly 999 variables
+	var inputs []string
+	for i := 1; i <= 250; i++ {
+		name := ""
+		if i <= 198 || i > 201 {
+			name = `, name: "new"`
+		}
+		inputs = append(inputs, fmt.Sprintf(`{clientMutationId: "%d", id: "%s"%s, updatedAt: "2020-01-01T00:00:00Z"}`, i, testID("Item", i), name))
+	}
+	response := s.do("", fmt.Sprintf(`mutation { updateManyItems(input: [%s]) { clientMutationId } }`, strings.Join(inputs, ", ")))
+	if len(response.Errors) > 0 {
+		t.Fatalf("unexpected errors %v", response.errorMessages())
+	}
+
+	if count := s.count("SELECT COUNT(*) FROM items WHERE updated_at > '2020-01-02'"); count != 250 {
+		t.Errorf("changed the versions of %d rows, expected 250", count)
+	}
+	if count := s.count("SELECT COUNT(*) FROM items WHERE name = 'new'"); count != 247 {
+		t.Errorf("changed the names of %d rows, expected 247", count)
+	}
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const whereSchema = `
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, age INTEGER);
+INSERT INTO users (name, age) VALUES ('a', 20), ('b', 30), ('c', NULL);
+`
+
+const whereConfig = testJWTConfig + `
+tables:
+  users:
+    policies:
+      update: id <> $claims.sub
+      delete: id <> $claims.sub
+`
+
+func TestMutationsWhere(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   map[string]interface{}
+		query   string
+		field   string
+		ids     []string
+		message string
+		check   string
+		count   int
+	}{
+		{
+			name:  "update",
+			query: `mutation { updateUsersWhere(filter: {age: {gte: 25}}, set: {name: "x"}) { affectedCount ids } }`,
+			field: "updateUsersWhere",
+			ids:   []string{testID("User", 2)},
+			check: "SELECT COUNT(*) FROM users WHERE name = 'x' AND id = 2",
+			count: 1,
+		},
+		{
+			name:  "update by or",
+			query: `mutation { updateUsersWhere(filter: {or: [{name: {eq: "a"}}, {age: {isNull: true}}]}, set: {age: 1}) { affectedCount ids } }`,
+			field: "updateUsersWhere",
+			ids:   []string{testID("User", 1), testID("User", 3)},
+			check: "SELECT COUNT(*) FROM users WHERE age = 1",
+			count: 2,
+		},
+		{
+			name:  "update restricted by the policy",
+			token: map[string]interface{}{"sub": 1},
+			query: `mutation { updateUsersWhere(filter: {}, all: true, set: {name: "x"}) { affectedCount ids } }`,
+			field: "updateUsersWhere",
+			ids:   []string{testID("User", 2), testID("User", 3)},
+			check: "SELECT COUNT(*) FROM users WHERE name = 'x' AND id <> 1",
+			count: 2,
+		},
+		{
+			name:  "delete by reference",
+			query: fmt.Sprintf(`mutation { deleteUsersWhere(filter: {id: {in: ["%s", "%s"]}}) { affectedCount ids } }`, testID("User", 1), testID("User", 2)),
+			field: "deleteUsersWhere",
+			ids:   []string{testID("User", 1), testID("User", 2)},
+			check: "SELECT COUNT(*) FROM users",
+			count: 1,
+		},
+		{
+			name:  "delete without matches",
+			query: `mutation { deleteUsersWhere(filter: {name: {eq: "z"}}) { affectedCount ids } }`,
+			field: "deleteUsersWhere",
+			ids:   []string{},
+			check: "SELECT COUNT(*) FROM users",
+			count: 3,
+		},
+		{
+			name:    "update without conditions",
+			query:   `mutation { updateUsersWhere(filter: {}, set: {name: "x"}) { affectedCount ids } }`,
+			message: "set all to confirm",
+			check:   "SELECT COUNT(*) FROM users WHERE name = 'x'",
+			count:   0,
+		},
+		{
+			name:    "delete by an and of filters without conditions",
+			query:   `mutation { deleteUsersWhere(filter: {and: [{}, {and: []}]}) { affectedCount ids } }`,
+			message: "set all to confirm",
+			check:   "SELECT COUNT(*) FROM users",
+			count:   3,
+		},
+		{
+			name:    "delete by an or of a filter without conditions",
+			query:   `mutation { deleteUsersWhere(filter: {or: [{}]}) { affectedCount ids } }`,
+			message: "set all to confirm",
+			check:   "SELECT COUNT(*) FROM users",
+			count:   3,
+		},
+		{
+			name:    "update by an or of a filter without conditions and a comparison",
+			query:   `mutation { updateUsersWhere(filter: {or: [{}, {name: {eq: "x"}}]}, set: {name: "y"}) { affectedCount ids } }`,
+			message: "set all to confirm",
+			check:   "SELECT COUNT(*) FROM users WHERE name = 'y'",
+			count:   0,
+		},
+		{
+			name:  "delete of all rows",
+			query: `mutation { deleteUsersWhere(filter: {}, all: true) { affectedCount ids } }`,
+			field: "deleteUsersWhere",
+			ids:   []string{testID("User", 1), testID("User", 2), testID("User", 3)},
+			check: "SELECT COUNT(*) FROM users",
+			count: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestServer(t, whereSchema, whereConfig)
+			defer s.Close()
+
+			// the policies of unauthenticated callers match no rows
+			claims := map[string]interface{}{"sub": 0}
+			if test.token != nil {
+				claims = test.token
+			}
+			response := s.do(testToken(t, claims), test.query)
+			if count := s.count(test.check); count != test.count {
+				t.Errorf("%s returned %d, expected %d", test.check, count, test.count)
+			}
+			switch messages := strings.Join(response.errorMessages(), "; "); {
+			case test.message == "" && messages != "":
+				t.Fatalf("unexpected errors %s", messages)
+			case !strings.Contains(messages, test.message):
+				t.Errorf("expected an error containing %q, got %q", test.message, messages)
+			}
+			if test.message != "" {
+				return
+			}
+
+			payload := response.Data[test.field].(map[string]interface{})
+			ids := []string{}
+			for _, id := range payload["ids"].([]interface{}) {
+				ids = append(ids, id.(string))
+			}
+			sort.Strings(ids)
+			sort.Strings(test.ids)
+			if !reflect.DeepEqual(ids, test.ids) {
+				t.Errorf("affected %v, expected %v", ids, test.ids)
+			}
+			if payload["affectedCount"] != float64(len(test.ids)) {
+				t.Errorf("affectedCount is %v, expected %d", payload["affectedCount"], len(test.ids))
+			}
+		})
+	}
+}
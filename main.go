@@ -2,6 +2,7 @@ package main
 
 import (
 	"dynamic-graphql-api/handler"
+	"flag"
 	"log"
 	"net/http"
 
@@ -9,7 +10,10 @@ import (
 )
 
 func main() {
-	h, err := handler.NewHandler("sqlite3", "test.db")
+	configPath := flag.String("config", "", "path to the configuration file (YAML or JSON)")
+	flag.Parse()
+
+	h, err := handler.NewHandler("sqlite3", "test.db", *configPath)
 	if err != nil {
 		panic(err)
 	}